@@ -2,14 +2,21 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/adapter/grpc"
 	"github.com/jpfigueredo/rate-limiter-challenge/internal/adapter/http"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/adapter/notifier"
 	"github.com/jpfigueredo/rate-limiter-challenge/internal/config"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/entity"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/repository"
 	"github.com/jpfigueredo/rate-limiter-challenge/internal/usecase"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/clock"
 	"github.com/jpfigueredo/rate-limiter-challenge/pkg/storage"
+	"github.com/redis/go-redis/v9"
 )
 
 func main() {
@@ -17,18 +24,126 @@ func main() {
 
 	cfg := config.Load()
 
-	repo := storage.NewRedisRateLimiter(cfg.RedisAddr)
-	if err := repo.Client.Ping(context.Background()).Err(); err != nil {
-		panic("Falha ao conectar Redis: " + err.Error())
+	repo, err := newRepository(context.Background(), cfg)
+	if err != nil {
+		panic("Falha ao conectar storage " + cfg.StorageBackend + ": " + err.Error())
 	}
 
 	uc := usecase.NewRateLimiterUseCase(repo, cfg.MaxRequests, cfg.MaxTokenRequests, time.Second, cfg.BlockDuration)
+	uc.Tenants = cfg.TenantLimits
+	tenants := tenantResolver(cfg)
+
+	if cfg.AdaptiveMax > 0 {
+		uc.Adaptive = usecase.NewAdaptiveLimiter(cfg.AdaptiveMin, cfg.AdaptiveMax, cfg.AdaptiveLatency, cfg.AdaptiveErrRate)
+		uc.Adaptive.Increase = cfg.AdaptiveIncrease
+		uc.Adaptive.DecreaseFactor = cfg.AdaptiveDecrease
+		uc.Adaptive.Interval = cfg.Window
+	}
+
+	if cfg.ShedCapacity > 0 {
+		uc.Shedder = usecase.NewLoadShedder(cfg.ShedCapacity)
+	}
+
+	if len(cfg.WaitTokens) > 0 {
+		uc.Queue = usecase.NewWaitQueue(cfg.WaitMax, cfg.WaitQueueDepth, cfg.WaitTokens...)
+	}
+
+	if sinks := eventSinks(cfg); len(sinks) > 0 {
+		dispatcher := notifier.NewDispatcher(cfg.EventsDebounce, 1024, sinks...)
+		uc.Events = dispatcher
+		go dispatcher.Run(context.Background())
+		go uc.RunEventSweeper(context.Background(), time.Second)
+	}
+
+	if cfg.GRPCPort != "" {
+		go func() {
+			if err := grpc.StartRateLimitServer(uc, cfg.GRPCPort); err != nil {
+				panic("Falha no servidor gRPC: " + err.Error())
+			}
+		}()
+	}
 
 	r := gin.Default()
-	r.Use(http.RateLimiterMiddleware(uc))
+	r.GET("/ratelimit/auth", http.AuthRequestHandler(uc, tenants))
+	r.GET("/metrics", http.MetricsHandler(uc))
 
-	r.GET("/ping", func(c *gin.Context) {
+	limiter := http.RateLimiterMiddlewareWithConfig(uc, http.MiddlewareConfig{
+		TenantResolver: tenants,
+		Priority: http.PriorityRules{
+			Header:    cfg.PriorityHeader,
+			Tokens:    cfg.PriorityTokens,
+			Routes:    cfg.PriorityRoutes,
+			Anonymous: entity.PriorityLow,
+		},
+	})
+	limited := r.Group("/", limiter)
+	limited.GET("/ping", func(c *gin.Context) {
 		c.JSON(200, gin.H{"message": "pong"})
 	})
+
+	if cfg.UpstreamURL != "" || len(cfg.UpstreamRoutes) > 0 {
+		proxy, err := http.ReverseProxyHandler(cfg.UpstreamURL, cfg.UpstreamRoutes)
+		if err != nil {
+			panic("Configuração de upstream inválida: " + err.Error())
+		}
+		r.NoRoute(limiter, proxy)
+	}
 	r.Run(":8080")
 }
+
+func tenantResolver(cfg *config.Config) http.TenantResolver {
+	switch cfg.TenantSource {
+	case "header":
+		return http.TenantFromHeader(cfg.TenantHeader)
+	case "host":
+		return http.TenantFromHost()
+	case "path":
+		return http.TenantFromPathPrefix()
+	default:
+		return nil
+	}
+}
+
+func newRepository(ctx context.Context, cfg *config.Config) (repository.RateLimiterRepository, error) {
+	switch cfg.StorageBackend {
+	case "redis":
+		repo := storage.NewRedisRateLimiter(cfg.RedisAddr)
+		if err := repo.Client.Ping(ctx).Err(); err != nil {
+			return nil, err
+		}
+		return repo, nil
+	case "postgres":
+		repo, err := storage.NewPostgresRateLimiter(ctx, cfg.PostgresDSN)
+		if err != nil {
+			return nil, err
+		}
+		go func() {
+			for range time.Tick(time.Minute) {
+				_ = repo.Purge(context.Background())
+			}
+		}()
+		return repo, nil
+	case "memcached":
+		repo := storage.NewMemcachedRateLimiter(cfg.MemcachedAddrs...)
+		if err := repo.Client.Ping(); err != nil {
+			return nil, err
+		}
+		return repo, nil
+	case "memory":
+		return storage.NewMemoryRateLimiter(clock.Real{}), nil
+	default:
+		return nil, fmt.Errorf("unknown backend %q", cfg.StorageBackend)
+	}
+}
+
+func eventSinks(cfg *config.Config) []notifier.Sink {
+	var sinks []notifier.Sink
+	if cfg.WebhookURL != "" {
+		sinks = append(sinks, notifier.NewWebhookSink(cfg.WebhookURL, cfg.WebhookSecret, cfg.WebhookRetries))
+	}
+	if cfg.EventsChannel != "" {
+		client := redis.NewClient(&redis.Options{Addr: cfg.RedisAddr})
+		sinks = append(sinks, notifier.NewRedisPubSubSink(client, cfg.EventsChannel))
+	}
+	return sinks
+}
@@ -0,0 +1,148 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	ratelimitcommon "github.com/envoyproxy/go-control-plane/envoy/extensions/common/ratelimit/v3"
+	rls "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/usecase"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	RemoteAddressKey = "remote_address"
+	APIKeyKey        = "api_key"
+)
+
+// RateLimitServer implements Envoy's ratelimit.v3 RateLimitService on top of
+// the rate limiter use case. Descriptors with a single remote_address or
+// api_key entry share counters with the HTTP middleware; any other descriptor
+// gets its own key scoped by domain.
+type RateLimitServer struct {
+	rls.UnimplementedRateLimitServiceServer
+	uc *usecase.RateLimiterUseCase
+}
+
+func NewRateLimitServer(uc *usecase.RateLimiterUseCase) *RateLimitServer {
+	return &RateLimitServer{uc: uc}
+}
+
+func (s *RateLimitServer) ShouldRateLimit(ctx context.Context, req *rls.RateLimitRequest) (*rls.RateLimitResponse, error) {
+	if req.GetDomain() == "" {
+		return nil, status.Error(codes.InvalidArgument, "domain is required")
+	}
+	if len(req.GetDescriptors()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "at least one descriptor is required")
+	}
+
+	resp := &rls.RateLimitResponse{OverallCode: rls.RateLimitResponse_OK}
+	for _, d := range req.GetDescriptors() {
+		key, max, window := s.resolve(req.GetDomain(), d)
+		allowed, err := s.uc.CheckKeyHits(ctx, key, max, window, hitsAddend(req, d))
+		if err != nil {
+			return nil, status.Error(codes.Unavailable, err.Error())
+		}
+
+		descStatus := &rls.RateLimitResponse_DescriptorStatus{
+			Code:         rls.RateLimitResponse_OK,
+			CurrentLimit: currentLimit(max, window),
+		}
+		if !allowed {
+			descStatus.Code = rls.RateLimitResponse_OVER_LIMIT
+			resp.OverallCode = rls.RateLimitResponse_OVER_LIMIT
+			if d, err := s.uc.BlockedFor(ctx, key); err == nil && d > 0 {
+				descStatus.DurationUntilReset = durationpb.New(d)
+			}
+		}
+		resp.Statuses = append(resp.Statuses, descStatus)
+	}
+	return resp, nil
+}
+
+// hitsAddend is the number of hits a descriptor counts for: its own
+// hits_addend if set, otherwise the request's, and at least one.
+func hitsAddend(req *rls.RateLimitRequest, d *ratelimitcommon.RateLimitDescriptor) int64 {
+	if h := d.GetHitsAddend(); h != nil {
+		return max(int64(h.GetValue()), 1)
+	}
+	return max(int64(req.GetHitsAddend()), 1)
+}
+
+func (s *RateLimitServer) resolve(domain string, d *ratelimitcommon.RateLimitDescriptor) (string, int64, time.Duration) {
+	entries := d.GetEntries()
+	key, max := DescriptorKey(domain, entries), s.uc.MaxRequests
+	for _, e := range entries {
+		if e.GetKey() == APIKeyKey {
+			max = s.uc.MaxTokenReqs
+		}
+	}
+	if len(entries) == 1 {
+		switch entries[0].GetKey() {
+		case RemoteAddressKey:
+			key = entries[0].GetValue()
+		case APIKeyKey:
+			key = "token:" + entries[0].GetValue()
+		}
+	}
+
+	window := s.uc.Window
+	if o := d.GetLimit(); o != nil && o.GetRequestsPerUnit() > 0 {
+		if unit, ok := unitDurations[o.GetUnit()]; ok {
+			max, window = int64(o.GetRequestsPerUnit()), unit
+		}
+	}
+	return key, max, window
+}
+
+// DescriptorKey builds the limiter key for a generic descriptor, e.g.
+// "envoy:edge:path=/orders|method=GET". Entry order is preserved because
+// Envoy treats descriptors as ordered.
+func DescriptorKey(domain string, entries []*ratelimitcommon.RateLimitDescriptor_Entry) string {
+	parts := make([]string, 0, len(entries))
+	for _, e := range entries {
+		parts = append(parts, e.GetKey()+"="+e.GetValue())
+	}
+	return "envoy:" + domain + ":" + strings.Join(parts, "|")
+}
+
+var unitDurations = map[typev3.RateLimitUnit]time.Duration{
+	typev3.RateLimitUnit_SECOND: time.Second,
+	typev3.RateLimitUnit_MINUTE: time.Minute,
+	typev3.RateLimitUnit_HOUR:   time.Hour,
+	typev3.RateLimitUnit_DAY:    24 * time.Hour,
+}
+
+var responseUnits = map[time.Duration]rls.RateLimitResponse_RateLimit_Unit{
+	time.Second:    rls.RateLimitResponse_RateLimit_SECOND,
+	time.Minute:    rls.RateLimitResponse_RateLimit_MINUTE,
+	time.Hour:      rls.RateLimitResponse_RateLimit_HOUR,
+	24 * time.Hour: rls.RateLimitResponse_RateLimit_DAY,
+}
+
+func currentLimit(max int64, window time.Duration) *rls.RateLimitResponse_RateLimit {
+	unit, ok := responseUnits[window]
+	if !ok {
+		return nil
+	}
+	return &rls.RateLimitResponse_RateLimit{RequestsPerUnit: uint32(max), Unit: unit}
+}
+
+func StartRateLimitServer(uc *usecase.RateLimiterUseCase, port string) error {
+	lis, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return err
+	}
+
+	server := grpc.NewServer()
+	rls.RegisterRateLimitServiceServer(server, NewRateLimitServer(uc))
+	reflection.Register(server)
+	return server.Serve(lis)
+}
@@ -0,0 +1,153 @@
+package grpc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	ratelimitcommon "github.com/envoyproxy/go-control-plane/envoy/extensions/common/ratelimit/v3"
+	rls "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	server "github.com/jpfigueredo/rate-limiter-challenge/internal/adapter/grpc"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/entity"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/usecase"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type countingRepo struct {
+	counts  map[string]int64
+	windows map[string]time.Duration
+	err     error
+}
+
+func newCountingRepo() *countingRepo {
+	return &countingRepo{counts: map[string]int64{}, windows: map[string]time.Duration{}}
+}
+
+func (m *countingRepo) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
+	m.counts[key]++
+	m.windows[key] = window
+	return m.counts[key], m.err
+}
+func (m *countingRepo) Block(ctx context.Context, key string, blockDuration time.Duration) error {
+	return nil
+}
+func (m *countingRepo) IsBlocked(ctx context.Context, key string) (bool, error) { return false, nil }
+func (m *countingRepo) GetState(ctx context.Context, key string) (*entity.RateLimit, error) {
+	return &entity.RateLimit{Key: key, Count: m.counts[key]}, nil
+}
+
+func descriptor(kv ...string) *ratelimitcommon.RateLimitDescriptor {
+	d := &ratelimitcommon.RateLimitDescriptor{}
+	for i := 0; i+1 < len(kv); i += 2 {
+		d.Entries = append(d.Entries, &ratelimitcommon.RateLimitDescriptor_Entry{Key: kv[i], Value: kv[i+1]})
+	}
+	return d
+}
+
+func TestShouldRateLimitSharesMiddlewareKeys(t *testing.T) {
+	repo := newCountingRepo()
+	uc := usecase.NewRateLimiterUseCase(repo, 1, 2, time.Second, time.Minute)
+	s := server.NewRateLimitServer(uc)
+
+	req := &rls.RateLimitRequest{Domain: "edge", Descriptors: []*ratelimitcommon.RateLimitDescriptor{
+		descriptor(server.RemoteAddressKey, "10.0.0.1"),
+		descriptor(server.APIKeyKey, "abc"),
+	}}
+	resp, err := s.ShouldRateLimit(context.Background(), req)
+	if err != nil || resp.OverallCode != rls.RateLimitResponse_OK || len(resp.Statuses) != 2 {
+		t.Fatalf("Expected OK for first request: %v %v", resp, err)
+	}
+	if repo.counts["10.0.0.1"] != 1 || repo.counts["token:abc"] != 1 {
+		t.Errorf("Expected descriptors to map to middleware keys, got %v", repo.counts)
+	}
+	if resp.Statuses[1].CurrentLimit.GetRequestsPerUnit() != 2 || resp.Statuses[1].CurrentLimit.GetUnit() != rls.RateLimitResponse_RateLimit_SECOND {
+		t.Errorf("Expected token limit in status, got %v", resp.Statuses[1].CurrentLimit)
+	}
+
+	resp, _ = s.ShouldRateLimit(context.Background(), req)
+	if resp.OverallCode != rls.RateLimitResponse_OVER_LIMIT || resp.Statuses[0].Code != rls.RateLimitResponse_OVER_LIMIT || resp.Statuses[1].Code != rls.RateLimitResponse_OK {
+		t.Errorf("Expected IP descriptor over limit and token still OK, got %v", resp)
+	}
+}
+
+func TestShouldRateLimitGenericDescriptorWithOverride(t *testing.T) {
+	repo := newCountingRepo()
+	uc := usecase.NewRateLimiterUseCase(repo, 1, 2, time.Second, time.Minute)
+	s := server.NewRateLimitServer(uc)
+
+	d := descriptor("path", "/orders", "method", "POST")
+	d.Limit = &ratelimitcommon.RateLimitDescriptor_RateLimitOverride{RequestsPerUnit: 3, Unit: typev3.RateLimitUnit_MINUTE}
+	req := &rls.RateLimitRequest{Domain: "edge", Descriptors: []*ratelimitcommon.RateLimitDescriptor{d}}
+
+	for i := 0; i < 3; i++ {
+		resp, err := s.ShouldRateLimit(context.Background(), req)
+		if err != nil || resp.OverallCode != rls.RateLimitResponse_OK {
+			t.Fatalf("Expected request %d allowed by override, got %v %v", i+1, resp, err)
+		}
+	}
+	resp, _ := s.ShouldRateLimit(context.Background(), req)
+	if resp.OverallCode != rls.RateLimitResponse_OVER_LIMIT {
+		t.Error("Expected over limit after override is exhausted")
+	}
+
+	key := "envoy:edge:path=/orders|method=POST"
+	if repo.counts[key] != 4 || repo.windows[key] != time.Minute {
+		t.Errorf("Expected generic key with minute window, got %v %v", repo.counts, repo.windows)
+	}
+}
+
+func TestShouldRateLimitCountsHitsAddend(t *testing.T) {
+	repo := newCountingRepo()
+	uc := usecase.NewRateLimiterUseCase(repo, 5, 10, time.Second, time.Minute)
+	s := server.NewRateLimitServer(uc)
+
+	own := descriptor(server.APIKeyKey, "abc")
+	own.HitsAddend = wrapperspb.UInt64(7)
+	zero := descriptor("path", "/zero")
+	zero.HitsAddend = wrapperspb.UInt64(0)
+	req := &rls.RateLimitRequest{Domain: "edge", HitsAddend: 4, Descriptors: []*ratelimitcommon.RateLimitDescriptor{
+		descriptor(server.RemoteAddressKey, "10.0.0.1"),
+		own,
+		zero,
+	}}
+	resp, err := s.ShouldRateLimit(context.Background(), req)
+	if err != nil || resp.OverallCode != rls.RateLimitResponse_OK {
+		t.Fatalf("Expected OK within limits, got %v %v", resp, err)
+	}
+	if repo.counts["10.0.0.1"] != 4 || repo.counts["token:abc"] != 7 || repo.counts["envoy:edge:path=/zero"] != 1 {
+		t.Errorf("Expected request, descriptor and minimum hits, got %v", repo.counts)
+	}
+
+	resp, _ = s.ShouldRateLimit(context.Background(), req)
+	if resp.Statuses[0].Code != rls.RateLimitResponse_OVER_LIMIT || resp.Statuses[1].Code != rls.RateLimitResponse_OVER_LIMIT || resp.Statuses[2].Code != rls.RateLimitResponse_OK {
+		t.Errorf("Expected batched hits to exhaust the limits, got %v", resp)
+	}
+}
+
+func TestShouldRateLimitInvalidRequest(t *testing.T) {
+	s := server.NewRateLimitServer(usecase.NewRateLimiterUseCase(newCountingRepo(), 1, 2, time.Second, time.Minute))
+
+	_, err := s.ShouldRateLimit(context.Background(), &rls.RateLimitRequest{Descriptors: []*ratelimitcommon.RateLimitDescriptor{descriptor("a", "b")}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Error("Expected InvalidArgument without domain")
+	}
+	_, err = s.ShouldRateLimit(context.Background(), &rls.RateLimitRequest{Domain: "edge"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Error("Expected InvalidArgument without descriptors")
+	}
+}
+
+func TestShouldRateLimitRepoError(t *testing.T) {
+	repo := newCountingRepo()
+	repo.err = errors.New("redis down")
+	s := server.NewRateLimitServer(usecase.NewRateLimiterUseCase(repo, 1, 2, time.Second, time.Minute))
+
+	_, err := s.ShouldRateLimit(context.Background(), &rls.RateLimitRequest{Domain: "edge", Descriptors: []*ratelimitcommon.RateLimitDescriptor{descriptor("a", "b")}})
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable on storage error, got %v", err)
+	}
+}
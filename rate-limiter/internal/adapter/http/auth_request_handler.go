@@ -0,0 +1,32 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/usecase"
+)
+
+// AuthRequestHandler answers NGINX auth_request subrequests. NGINX only
+// accepts 2xx, 401 and 403 from the subrequest, so a limited client gets 403
+// and the X-RateLimit-Limited header; map it to 429 with error_page.
+func AuthRequestHandler(uc *usecase.RateLimiterUseCase, tenantResolver TenantResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip := c.ClientIP()
+		token := c.GetHeader("API_KEY")
+		tenant := resolveTenant(tenantResolver, c)
+
+		allowed, err := uc.CheckAndIncrementForTenant(c.Request.Context(), tenant, ip, token)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+			return
+		}
+		if !allowed {
+			c.Header("X-RateLimit-Limited", "true")
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,40 @@
+package http_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	middleware "github.com/jpfigueredo/rate-limiter-challenge/internal/adapter/http"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/repository"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/usecase"
+)
+
+func serveAuthRequest(repo repository.RateLimiterRepository) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	uc := usecase.NewRateLimiterUseCase(repo, 1, 10, time.Second, 5*time.Minute)
+	r.GET("/ratelimit/auth", middleware.AuthRequestHandler(uc, nil))
+
+	req, _ := http.NewRequest("GET", "/ratelimit/auth", nil)
+	req.Header.Set("X-Original-URI", "/orders")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthRequestAllowed(t *testing.T) {
+	w := serveAuthRequest(&mockRepo{})
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 for allowed subrequest, got %d", w.Code)
+	}
+}
+
+func TestAuthRequestLimited(t *testing.T) {
+	w := serveAuthRequest(&mockRepoBlocked{})
+	if w.Code != http.StatusForbidden || w.Header().Get("X-RateLimit-Limited") != "true" {
+		t.Errorf("Expected 403 with limited header, got %d", w.Code)
+	}
+}
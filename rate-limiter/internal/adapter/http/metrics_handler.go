@@ -0,0 +1,37 @@
+package http
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/usecase"
+)
+
+// MetricsHandler exposes the adaptive limiter state in the Prometheus text
+// format.
+func MetricsHandler(uc *usecase.RateLimiterUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Content-Type", "text/plain; version=0.0.4")
+		if uc.Adaptive == nil {
+			c.Status(200)
+			return
+		}
+
+		stats := uc.Adaptive.Stats()
+		fmt.Fprintf(c.Writer, "# HELP rate_limiter_adaptive_limit Current effective global limit per window.\n")
+		fmt.Fprintf(c.Writer, "# TYPE rate_limiter_adaptive_limit gauge\n")
+		fmt.Fprintf(c.Writer, "rate_limiter_adaptive_limit %d\n", stats.Limit)
+		fmt.Fprintf(c.Writer, "# HELP rate_limiter_adaptive_limit_floor Lower bound of the adaptive limit.\n")
+		fmt.Fprintf(c.Writer, "# TYPE rate_limiter_adaptive_limit_floor gauge\n")
+		fmt.Fprintf(c.Writer, "rate_limiter_adaptive_limit_floor %d\n", uc.Adaptive.Floor)
+		fmt.Fprintf(c.Writer, "# HELP rate_limiter_adaptive_limit_ceiling Upper bound of the adaptive limit.\n")
+		fmt.Fprintf(c.Writer, "# TYPE rate_limiter_adaptive_limit_ceiling gauge\n")
+		fmt.Fprintf(c.Writer, "rate_limiter_adaptive_limit_ceiling %d\n", uc.Adaptive.Ceiling)
+		fmt.Fprintf(c.Writer, "# HELP rate_limiter_upstream_latency_seconds Mean upstream latency in the last interval.\n")
+		fmt.Fprintf(c.Writer, "# TYPE rate_limiter_upstream_latency_seconds gauge\n")
+		fmt.Fprintf(c.Writer, "rate_limiter_upstream_latency_seconds %g\n", stats.MeanLatency.Seconds())
+		fmt.Fprintf(c.Writer, "# HELP rate_limiter_upstream_error_rate Share of 5xx upstream responses in the last interval.\n")
+		fmt.Fprintf(c.Writer, "# TYPE rate_limiter_upstream_error_rate gauge\n")
+		fmt.Fprintf(c.Writer, "rate_limiter_upstream_error_rate %g\n", stats.ErrorRate)
+	}
+}
@@ -0,0 +1,54 @@
+package http_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	middleware "github.com/jpfigueredo/rate-limiter-challenge/internal/adapter/http"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/usecase"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/clock"
+)
+
+func TestMiddlewareFeedsAdaptiveLimiter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	clk := clock.NewFake(time.Unix(0, 0))
+	uc := usecase.NewRateLimiterUseCase(&mockRepo{}, 5, 10, time.Second, 5*time.Minute)
+	uc.Clock = clk
+	uc.Adaptive = usecase.NewAdaptiveLimiter(2, 20, time.Second, 0.1)
+	uc.Adaptive.Clock = clk
+	uc.Adaptive.Limit()
+
+	r := gin.New()
+	r.Use(middleware.RateLimiterMiddleware(uc))
+	r.GET("/fail", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+	r.GET("/metrics", middleware.MetricsHandler(uc))
+
+	for i := 0; i < 3; i++ {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fail", nil))
+	}
+	clk.Advance(time.Second)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	if !strings.Contains(body, "rate_limiter_adaptive_limit 10\n") || !strings.Contains(body, "rate_limiter_upstream_error_rate 1\n") {
+		t.Errorf("Expected limit halved after upstream errors, got:\n%s", body)
+	}
+}
+
+func TestMetricsWithoutAdaptive(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	uc := usecase.NewRateLimiterUseCase(&mockRepo{}, 5, 10, time.Second, 5*time.Minute)
+	r := gin.New()
+	r.GET("/metrics", middleware.MetricsHandler(uc))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code != 200 || strings.Contains(w.Body.String(), "adaptive") {
+		t.Errorf("Expected empty metrics without adaptive mode, got %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/entity"
+)
+
+// PriorityRules derives the priority class of a request. The first match
+// wins: the API token profile, then the longest route prefix, and finally
+// Anonymous. Any client can send an API_KEY, so only tokens listed in Tokens
+// raise the class; unknown ones are anonymous. The optional priority header
+// is client controlled too, so it can only lower the class, e.g. for
+// background jobs.
+type PriorityRules struct {
+	Header    string
+	Tokens    map[string]entity.Priority
+	Routes    map[string]entity.Priority
+	Anonymous entity.Priority
+}
+
+func (p PriorityRules) Resolve(c *gin.Context) entity.Priority {
+	priority := p.resolve(c)
+	if p.Header != "" {
+		if requested, ok := entity.ParsePriority(c.GetHeader(p.Header)); ok && requested < priority {
+			return requested
+		}
+	}
+	return priority
+}
+
+func (p PriorityRules) resolve(c *gin.Context) entity.Priority {
+	token := c.GetHeader("API_KEY")
+	if priority, ok := p.Tokens[token]; ok && token != "" {
+		return priority
+	}
+
+	prefixes := make([]string, 0, len(p.Routes))
+	for prefix := range p.Routes {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+	for _, prefix := range prefixes {
+		if matchesPrefix(c.Request.URL.Path, prefix) {
+			return p.Routes[prefix]
+		}
+	}
+	return p.Anonymous
+}
@@ -0,0 +1,95 @@
+package http_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	middleware "github.com/jpfigueredo/rate-limiter-challenge/internal/adapter/http"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/entity"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/usecase"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/clock"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/storage"
+)
+
+var rules = middleware.PriorityRules{
+	Header:    "X-Priority",
+	Tokens:    map[string]entity.Priority{"gold": entity.PriorityCritical, "silver": entity.PriorityNormal},
+	Routes:    map[string]entity.Priority{"/admin": entity.PriorityHigh, "/admin/reports": entity.PriorityLow},
+	Anonymous: entity.PriorityLow,
+}
+
+func TestPriorityRules(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cases := []struct {
+		name     string
+		path     string
+		token    string
+		header   string
+		expected entity.Priority
+	}{
+		{"token profile wins", "/admin", "gold", "", entity.PriorityCritical},
+		{"header cannot raise anonymous", "/orders", "", "critical", entity.PriorityLow},
+		{"header cannot raise token profile", "/orders", "silver", "high", entity.PriorityNormal},
+		{"header lowers", "/admin", "gold", "low", entity.PriorityLow},
+		{"invalid header ignored", "/admin", "", "urgent", entity.PriorityHigh},
+		{"longest route", "/admin/reports/1", "", "", entity.PriorityLow},
+		{"route", "/admin/users", "", "", entity.PriorityHigh},
+		{"route matches whole segments", "/administrator", "", "", entity.PriorityLow},
+		{"known token", "/orders", "silver", "", entity.PriorityNormal},
+		{"unknown token is anonymous", "/orders", "made-up", "", entity.PriorityLow},
+		{"unknown token gets the route class", "/admin", "made-up", "", entity.PriorityHigh},
+		{"anonymous", "/orders", "", "", entity.PriorityLow},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", tc.path, nil)
+			if tc.token != "" {
+				c.Request.Header.Set("API_KEY", tc.token)
+			}
+			if tc.header != "" {
+				c.Request.Header.Set("X-Priority", tc.header)
+			}
+			if got := rules.Resolve(c); got != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestMiddlewareShedsAnonymousBeforePaying(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	clk := clock.NewFake(time.Unix(0, 0))
+	uc := usecase.NewRateLimiterUseCase(storage.NewMemoryRateLimiter(clk), 100, 100, time.Second, time.Minute)
+	uc.Clock = clk
+	uc.Shedder = usecase.NewLoadShedder(4)
+
+	r := gin.New()
+	r.Use(middleware.RateLimiterMiddlewareWithConfig(uc, middleware.MiddlewareConfig{Priority: rules}))
+	r.GET("/orders", func(c *gin.Context) { c.Status(200) })
+
+	send := func(token string) int {
+		req := httptest.NewRequest("GET", "/orders", nil)
+		if token != "" {
+			req.Header.Set("API_KEY", token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	codes := []int{send(""), send(""), send("")}
+	if codes[0] != 200 || codes[1] != 200 || codes[2] != http.StatusServiceUnavailable {
+		t.Errorf("Expected anonymous traffic shed after half the budget, got %v", codes)
+	}
+	if code := send("made-up"); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected an unknown API_KEY shed like anonymous traffic, got %d", code)
+	}
+	if code := send("gold"); code != 200 {
+		t.Errorf("Expected paying customer admitted, got %d", code)
+	}
+}
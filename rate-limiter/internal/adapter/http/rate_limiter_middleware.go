@@ -7,12 +7,27 @@ import (
 	"github.com/jpfigueredo/rate-limiter-challenge/internal/usecase"
 )
 
+type MiddlewareConfig struct {
+	TenantResolver TenantResolver
+	Priority       PriorityRules
+}
+
 func RateLimiterMiddleware(uc *usecase.RateLimiterUseCase) gin.HandlerFunc {
+	return RateLimiterMiddlewareWithConfig(uc, MiddlewareConfig{})
+}
+
+func RateLimiterMiddlewareWithConfig(uc *usecase.RateLimiterUseCase, cfg MiddlewareConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ip := c.ClientIP()
 		token := c.GetHeader("API_KEY")
+		tenant := resolveTenant(cfg.TenantResolver, c)
 
-		allowed, err := uc.CheckAndIncrement(c.Request.Context(), ip, token)
+		allowed, err := uc.CheckAndWaitForTenant(c.Request.Context(), tenant, ip, token)
+		if err != nil && c.Request.Context().Err() != nil {
+			// The client went away while waiting for a slot.
+			c.Abort()
+			return
+		}
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
 			return
@@ -24,6 +39,25 @@ func RateLimiterMiddleware(uc *usecase.RateLimiterUseCase) gin.HandlerFunc {
 			return
 		}
 
+		if uc.Shedder != nil {
+			admitted, err := uc.Admit(c.Request.Context(), cfg.Priority.Resolve(c))
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+				return
+			}
+			if !admitted {
+				c.Header("Retry-After", "1")
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "server is overloaded, try again later"})
+				return
+			}
+		}
+
+		if uc.Adaptive == nil {
+			c.Next()
+			return
+		}
+		start := uc.Clock.Now()
 		c.Next()
+		uc.Adaptive.Observe(uc.Clock.Now().Sub(start), c.Writer.Status())
 	}
 }
@@ -11,6 +11,8 @@ import (
 	middleware "github.com/jpfigueredo/rate-limiter-challenge/internal/adapter/http"
 	"github.com/jpfigueredo/rate-limiter-challenge/internal/entity"
 	"github.com/jpfigueredo/rate-limiter-challenge/internal/usecase"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/clock"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/storage"
 )
 
 type mockRepo struct{}
@@ -71,3 +73,48 @@ func (m *mockRepoBlocked) IsBlocked(ctx context.Context, key string) (bool, erro
 func (m *mockRepoBlocked) GetState(ctx context.Context, key string) (*entity.RateLimit, error) {
 	return &entity.RateLimit{}, nil
 }
+
+func TestMiddlewareWaitsForListedTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	uc := usecase.NewRateLimiterUseCase(storage.NewMemoryRateLimiter(clock.Real{}), 1, 1, 100*time.Millisecond, 5*time.Minute)
+	uc.Queue = usecase.NewWaitQueue(time.Second, 10, "internal")
+	uc.Queue.Poll = 10 * time.Millisecond
+
+	r := gin.New()
+	var served int
+	r.Use(middleware.RateLimiterMiddleware(uc))
+	r.GET("/test", func(c *gin.Context) {
+		served++
+		c.Status(200)
+	})
+
+	send := func(ctx context.Context, token string) int {
+		req := httptest.NewRequest("GET", "/test", nil).WithContext(ctx)
+		req.Header.Set("API_KEY", token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	ctx := context.Background()
+	if code := send(ctx, "internal"); code != 200 {
+		t.Fatalf("Expected first request allowed, got %d", code)
+	}
+	if code := send(ctx, "internal"); code != 200 {
+		t.Errorf("Expected second request held then allowed, got %d", code)
+	}
+
+	send(ctx, "external")
+	if code := send(ctx, "external"); code != 429 {
+		t.Errorf("Expected unlisted token rejected immediately, got %d", code)
+	}
+
+	cancelled, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	send(ctx, "internal")
+	before := served
+	send(cancelled, "internal")
+	if served != before {
+		t.Error("Expected abandoned request not served")
+	}
+}
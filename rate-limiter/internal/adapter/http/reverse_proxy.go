@@ -0,0 +1,88 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type upstreamRoute struct {
+	prefix string
+	proxy  *httputil.ReverseProxy
+}
+
+// ReverseProxyHandler forwards requests to the upstream whose prefix is the
+// longest match for the request path, falling back to defaultUpstream.
+// Prefixes match whole path segments, so "/orders" does not match "/ordersX".
+func ReverseProxyHandler(defaultUpstream string, routes map[string]string) (gin.HandlerFunc, error) {
+	var fallback *httputil.ReverseProxy
+	if defaultUpstream != "" {
+		p, err := newUpstreamProxy(defaultUpstream)
+		if err != nil {
+			return nil, err
+		}
+		fallback = p
+	}
+
+	var table []upstreamRoute
+	for prefix, upstream := range routes {
+		p, err := newUpstreamProxy(upstream)
+		if err != nil {
+			return nil, err
+		}
+		table = append(table, upstreamRoute{prefix: prefix, proxy: p})
+	}
+	sort.Slice(table, func(i, j int) bool {
+		return len(table[i].prefix) > len(table[j].prefix)
+	})
+
+	return func(c *gin.Context) {
+		proxy := fallback
+		for _, r := range table {
+			if matchesPrefix(c.Request.URL.Path, r.prefix) {
+				proxy = r.proxy
+				break
+			}
+		}
+		if proxy == nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "no upstream configured for this path"})
+			return
+		}
+		proxy.ServeHTTP(c.Writer, c.Request)
+	}, nil
+}
+
+// matchesPrefix reports whether path is prefix or lies below it.
+func matchesPrefix(path, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || strings.HasSuffix(prefix, "/") || path[len(prefix)] == '/'
+}
+
+func newUpstreamProxy(upstream string) (*httputil.ReverseProxy, error) {
+	target, err := url.Parse(upstream)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upstream %q: %w", upstream, err)
+	}
+	if target.Scheme == "" || target.Host == "" {
+		return nil, fmt.Errorf("invalid upstream %q: scheme and host are required", upstream)
+	}
+
+	return &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(target)
+			r.SetXForwarded()
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte(`{"error":"upstream unavailable"}`))
+		},
+	}, nil
+}
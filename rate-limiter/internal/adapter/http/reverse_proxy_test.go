@@ -0,0 +1,117 @@
+package http_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	middleware "github.com/jpfigueredo/rate-limiter-challenge/internal/adapter/http"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/usecase"
+)
+
+func newUpstream(t *testing.T, name string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, name+":"+r.URL.Path)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newProxyServer(t *testing.T, uc *usecase.RateLimiterUseCase, defaultUpstream string, routes map[string]string) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+	proxy, err := middleware.ReverseProxyHandler(defaultUpstream, routes)
+	if err != nil {
+		t.Fatalf("Expected proxy to be created: %v", err)
+	}
+	r := gin.New()
+	r.Use(middleware.RateLimiterMiddleware(uc))
+	r.NoRoute(proxy)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func get(t *testing.T, srv *httptest.Server, path string) (int, string) {
+	resp, err := http.Get(srv.URL + path)
+	if err != nil {
+		t.Fatalf("Expected request to succeed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestReverseProxyRoutesByLongestPrefix(t *testing.T) {
+	def := newUpstream(t, "default")
+	orders := newUpstream(t, "orders")
+	items := newUpstream(t, "items")
+	uc := usecase.NewRateLimiterUseCase(&mockRepo{}, 5, 10, time.Second, 5*time.Minute)
+	srv := newProxyServer(t, uc, def.URL, map[string]string{"/orders": orders.URL, "/orders/items": items.URL})
+
+	cases := map[string]string{
+		"/orders":         "orders:/orders",
+		"/orders/1":       "orders:/orders/1",
+		"/orders/items":   "items:/orders/items",
+		"/orders/items/2": "items:/orders/items/2",
+		"/ordersX":        "default:/ordersX",
+		"/orders/itemsX":  "orders:/orders/itemsX",
+		"/patients":       "default:/patients",
+	}
+	for path, expected := range cases {
+		code, body := get(t, srv, path)
+		if code != 200 || body != expected {
+			t.Errorf("Expected %s for %s, got %d %s", expected, path, code, body)
+		}
+	}
+}
+
+func TestReverseProxyBlockedNotForwarded(t *testing.T) {
+	hits := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hits++ }))
+	defer upstream.Close()
+	uc := usecase.NewRateLimiterUseCase(&mockRepoBlocked{}, 1, 10, time.Second, 5*time.Minute)
+	srv := newProxyServer(t, uc, upstream.URL, nil)
+
+	code, _ := get(t, srv, "/anything")
+
+	if code != 429 || hits != 0 {
+		t.Errorf("Expected 429 without forwarding, got %d and %d upstream hits", code, hits)
+	}
+}
+
+func TestReverseProxyNoUpstream(t *testing.T) {
+	uc := usecase.NewRateLimiterUseCase(&mockRepo{}, 5, 10, time.Second, 5*time.Minute)
+	srv := newProxyServer(t, uc, "", map[string]string{"/orders": "http://localhost:1"})
+
+	code, _ := get(t, srv, "/patients")
+
+	if code != 404 {
+		t.Errorf("Expected 404 without matching upstream, got %d", code)
+	}
+}
+
+func TestReverseProxyUpstreamDown(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	addr := upstream.URL
+	upstream.Close()
+	uc := usecase.NewRateLimiterUseCase(&mockRepo{}, 5, 10, time.Second, 5*time.Minute)
+	srv := newProxyServer(t, uc, addr, nil)
+
+	code, _ := get(t, srv, "/orders")
+
+	if code != 502 {
+		t.Errorf("Expected 502 when upstream is down, got %d", code)
+	}
+}
+
+func TestReverseProxyInvalidUpstream(t *testing.T) {
+	if _, err := middleware.ReverseProxyHandler("not-a-url", nil); err == nil {
+		t.Error("Expected error for invalid default upstream")
+	}
+	if _, err := middleware.ReverseProxyHandler("", map[string]string{"/x": "://bad"}); err == nil {
+		t.Error("Expected error for invalid route upstream")
+	}
+}
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"net"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TenantResolver extracts the tenant of a request; an empty tenant means the
+// request is limited with the default, non-namespaced keys.
+type TenantResolver func(c *gin.Context) string
+
+func TenantFromHeader(name string) TenantResolver {
+	return func(c *gin.Context) string {
+		return c.GetHeader(name)
+	}
+}
+
+func TenantFromHost() TenantResolver {
+	return func(c *gin.Context) string {
+		host := c.Request.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		return strings.ToLower(host)
+	}
+}
+
+// TenantFromPathPrefix uses the first path segment, e.g. "acme" for
+// "/acme/orders". Behind NGINX auth_request the original path comes in
+// X-Original-URI.
+func TenantFromPathPrefix() TenantResolver {
+	return func(c *gin.Context) string {
+		path := c.Request.URL.Path
+		if original := c.GetHeader("X-Original-URI"); original != "" {
+			path = original
+		}
+		segment, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+		segment, _, _ = strings.Cut(segment, "?")
+		return segment
+	}
+}
+
+func resolveTenant(resolver TenantResolver, c *gin.Context) string {
+	if resolver == nil {
+		return ""
+	}
+	return resolver(c)
+}
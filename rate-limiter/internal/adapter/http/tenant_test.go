@@ -0,0 +1,84 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	middleware "github.com/jpfigueredo/rate-limiter-challenge/internal/adapter/http"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/entity"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/usecase"
+)
+
+type keyRecordingRepo struct {
+	keys []string
+}
+
+func (m *keyRecordingRepo) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
+	m.keys = append(m.keys, key)
+	return 1, nil
+}
+func (m *keyRecordingRepo) Block(ctx context.Context, key string, blockDuration time.Duration) error {
+	return nil
+}
+func (m *keyRecordingRepo) IsBlocked(ctx context.Context, key string) (bool, error) {
+	return false, nil
+}
+func (m *keyRecordingRepo) GetState(ctx context.Context, key string) (*entity.RateLimit, error) {
+	return &entity.RateLimit{}, nil
+}
+
+func TestTenantResolvers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cases := []struct {
+		name     string
+		resolver middleware.TenantResolver
+		target   string
+		header   map[string]string
+		expected string
+	}{
+		{"header", middleware.TenantFromHeader("X-Tenant-ID"), "/orders", map[string]string{"X-Tenant-ID": "acme"}, "acme"},
+		{"missing header", middleware.TenantFromHeader("X-Tenant-ID"), "/orders", nil, ""},
+		{"host", middleware.TenantFromHost(), "http://Acme.example.com:8080/orders", nil, "acme.example.com"},
+		{"path prefix", middleware.TenantFromPathPrefix(), "/globex/orders/1", nil, "globex"},
+		{"original uri", middleware.TenantFromPathPrefix(), "/ratelimit/auth", map[string]string{"X-Original-URI": "/initech/orders?x=1"}, "initech"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", tc.target, nil)
+			for k, v := range tc.header {
+				c.Request.Header.Set(k, v)
+			}
+			if got := tc.resolver(c); got != tc.expected {
+				t.Errorf("Expected tenant %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestMiddlewareNamespacesKeysByTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &keyRecordingRepo{}
+	uc := usecase.NewRateLimiterUseCase(repo, 5, 10, time.Second, 5*time.Minute)
+	uc.Tenants = map[string]entity.TenantLimits{"acme": {}}
+	r := gin.New()
+	r.Use(middleware.RateLimiterMiddlewareWithConfig(uc, middleware.MiddlewareConfig{
+		TenantResolver: middleware.TenantFromHeader("X-Tenant-ID"),
+	}))
+	r.GET("/test", func(c *gin.Context) { c.Status(200) })
+
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Tenant-ID", "acme")
+	req.Header.Set("API_KEY", "mytoken")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != 200 || len(repo.keys) != 1 || repo.keys[0] != "tenant:acme:token:mytoken" {
+		t.Errorf("Expected tenant namespaced key, got %d %v", w.Code, repo.keys)
+	}
+}
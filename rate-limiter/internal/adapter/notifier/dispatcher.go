@@ -0,0 +1,154 @@
+package notifier
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/entity"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/usecase"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/clock"
+)
+
+type Sink interface {
+	Send(ctx context.Context, event entity.Event) error
+}
+
+// Dispatcher fans events out to the sinks in the background so the request
+// path never waits on a webhook. Each sink has its own queue and goroutine,
+// so a slow sink does not delay the others. Events are debounced per key:
+// inside the interval a repeat of the last state sent is dropped, and a key
+// flapping between states only has its latest state held back and sent once
+// the interval ends, so subscribers always finish in the right state.
+type Dispatcher struct {
+	Sinks    []Sink
+	Debounce time.Duration
+	Clock    clock.Clock
+
+	queue   chan entity.Event
+	mu      sync.Mutex
+	last    map[string]sentEvent
+	pending map[string]entity.Event
+}
+
+type sentEvent struct {
+	Type entity.EventType
+	At   time.Time
+}
+
+var _ usecase.EventPublisher = (*Dispatcher)(nil)
+
+func NewDispatcher(debounce time.Duration, bufferSize int, sinks ...Sink) *Dispatcher {
+	return &Dispatcher{
+		Sinks:    sinks,
+		Debounce: debounce,
+		Clock:    clock.Real{},
+		queue:    make(chan entity.Event, bufferSize),
+		last:     make(map[string]sentEvent),
+		pending:  make(map[string]entity.Event),
+	}
+}
+
+func (d *Dispatcher) Publish(ctx context.Context, event entity.Event) error {
+	if !d.admit(event) {
+		return nil
+	}
+	select {
+	case d.queue <- event:
+	default:
+		log.Printf("notifier: queue full, dropping %s event for %s", event.Type, event.Key)
+	}
+	return nil
+}
+
+// Run delivers queued events until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	queues := make([]chan entity.Event, len(d.Sinks))
+	for i, sink := range d.Sinks {
+		queues[i] = make(chan entity.Event, cap(d.queue))
+		go deliver(ctx, sink, queues[i])
+	}
+	var tick <-chan time.Time
+	if d.Debounce > 0 {
+		tick = d.Clock.After(d.Debounce)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-d.queue:
+			fanOut(queues, event)
+		case <-tick:
+			for _, event := range d.flush() {
+				fanOut(queues, event)
+			}
+			tick = d.Clock.After(d.Debounce)
+		}
+	}
+}
+
+func fanOut(queues []chan entity.Event, event entity.Event) {
+	for i, q := range queues {
+		select {
+		case q <- event:
+		default:
+			log.Printf("notifier: sink %d queue full, dropping %s event for %s", i, event.Type, event.Key)
+		}
+	}
+}
+
+func deliver(ctx context.Context, sink Sink, queue <-chan entity.Event) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-queue:
+			if err := sink.Send(ctx, event); err != nil {
+				log.Printf("notifier: %s event for %s not delivered: %v", event.Type, event.Key, err)
+			}
+		}
+	}
+}
+
+func (d *Dispatcher) admit(event entity.Event) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.Clock.Now()
+	if last, ok := d.last[event.Key]; ok && now.Sub(last.At) < d.Debounce {
+		if event.Type == last.Type {
+			delete(d.pending, event.Key)
+		} else {
+			d.pending[event.Key] = event
+		}
+		return false
+	}
+	delete(d.pending, event.Key)
+	d.last[event.Key] = sentEvent{Type: event.Type, At: now}
+
+	for k, sent := range d.last {
+		if _, held := d.pending[k]; !held && now.Sub(sent.At) >= d.Debounce {
+			delete(d.last, k)
+		}
+	}
+	return true
+}
+
+// flush releases the held back states whose debounce interval has ended.
+func (d *Dispatcher) flush() []entity.Event {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.Clock.Now()
+	var due []entity.Event
+	for key, event := range d.pending {
+		if now.Sub(d.last[key].At) < d.Debounce {
+			continue
+		}
+		delete(d.pending, key)
+		d.last[key] = sentEvent{Type: event.Type, At: now}
+		due = append(due, event)
+	}
+	return due
+}
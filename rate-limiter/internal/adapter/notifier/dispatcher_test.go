@@ -0,0 +1,164 @@
+package notifier_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/adapter/notifier"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/entity"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/clock"
+)
+
+type recordingSink struct {
+	mu     sync.Mutex
+	events []entity.Event
+	done   chan struct{}
+}
+
+func newRecordingSink(expected int) *recordingSink {
+	return &recordingSink{done: make(chan struct{}, expected)}
+}
+
+func (s *recordingSink) Send(ctx context.Context, event entity.Event) error {
+	s.mu.Lock()
+	s.events = append(s.events, event)
+	s.mu.Unlock()
+	s.done <- struct{}{}
+	return nil
+}
+
+func (s *recordingSink) wait(t *testing.T, n int) []entity.Event {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-s.done:
+		case <-time.After(time.Second):
+			t.Fatalf("Expected %d events, got %d", n, i)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]entity.Event(nil), s.events...)
+}
+
+func TestDispatcherDebouncesHotKeys(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	sink := newRecordingSink(10)
+	d := notifier.NewDispatcher(time.Minute, 10, sink)
+	d.Clock = clk
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.Run(ctx)
+
+	blocked := entity.Event{Type: entity.EventBlocked, Key: "10.0.0.1"}
+	_ = d.Publish(ctx, blocked)
+	_ = d.Publish(ctx, blocked)
+	_ = d.Publish(ctx, entity.Event{Type: entity.EventBlocked, Key: "10.0.0.2"})
+	clk.Advance(time.Minute)
+	_ = d.Publish(ctx, blocked)
+
+	events := sink.wait(t, 3)
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events after debounce, got %d", len(events))
+	}
+	if events[2].Key != "10.0.0.1" || events[2].Type != entity.EventBlocked {
+		t.Errorf("Expected hot key event again after debounce interval, got %+v", events[2])
+	}
+}
+
+func TestDispatcherCoalescesFlappingKeys(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	sink := newRecordingSink(10)
+	d := notifier.NewDispatcher(time.Minute, 10, sink)
+	d.Clock = clk
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.Run(ctx)
+	for clk.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	for _, typ := range []entity.EventType{entity.EventBlocked, entity.EventUnblocked, entity.EventBlocked, entity.EventUnblocked} {
+		_ = d.Publish(ctx, entity.Event{Type: typ, Key: "10.0.0.1"})
+	}
+	if events := sink.wait(t, 1); len(events) != 1 || events[0].Type != entity.EventBlocked {
+		t.Fatalf("Expected only the first blocked event inside the debounce, got %+v", events)
+	}
+
+	clk.Advance(time.Minute)
+	events := sink.wait(t, 1)
+	if len(events) != 2 || events[1].Type != entity.EventUnblocked {
+		t.Errorf("Expected the held back unblocked state after the debounce, got %+v", events)
+	}
+}
+
+func TestDispatcherDropsFlapBackToSentState(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	sink := newRecordingSink(10)
+	d := notifier.NewDispatcher(time.Minute, 10, sink)
+	d.Clock = clk
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.Run(ctx)
+	for clk.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	for _, typ := range []entity.EventType{entity.EventBlocked, entity.EventUnblocked, entity.EventBlocked} {
+		_ = d.Publish(ctx, entity.Event{Type: typ, Key: "10.0.0.1"})
+	}
+	clk.Advance(time.Minute)
+	for clk.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	_ = d.Publish(ctx, entity.Event{Type: entity.EventUnblocked, Key: "10.0.0.2"})
+
+	events := sink.wait(t, 2)
+	if len(events) != 2 || events[0].Key != "10.0.0.1" || events[1].Key != "10.0.0.2" {
+		t.Errorf("Expected no second event for a key that flapped back, got %+v", events)
+	}
+}
+
+type stuckSink struct{}
+
+func (stuckSink) Send(ctx context.Context, event entity.Event) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestDispatcherSlowSinkDoesNotDelayOthers(t *testing.T) {
+	fast := newRecordingSink(2)
+	d := notifier.NewDispatcher(0, 10, stuckSink{}, fast)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.Run(ctx)
+
+	_ = d.Publish(ctx, entity.Event{Type: entity.EventBlocked, Key: "a"})
+	_ = d.Publish(ctx, entity.Event{Type: entity.EventBlocked, Key: "b"})
+	if len(fast.wait(t, 2)) != 2 {
+		t.Error("Expected the fast sink to receive both events")
+	}
+}
+
+func TestDispatcherFansOutToAllSinks(t *testing.T) {
+	a, b := newRecordingSink(1), newRecordingSink(1)
+	d := notifier.NewDispatcher(0, 1, a, b)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.Run(ctx)
+
+	_ = d.Publish(ctx, entity.Event{Type: entity.EventBlocked, Key: "k"})
+	if len(a.wait(t, 1)) != 1 || len(b.wait(t, 1)) != 1 {
+		t.Error("Expected every sink to receive the event")
+	}
+}
+
+func TestDispatcherDropsWhenQueueFull(t *testing.T) {
+	d := notifier.NewDispatcher(0, 1)
+	_ = d.Publish(context.Background(), entity.Event{Type: entity.EventBlocked, Key: "a"})
+	if err := d.Publish(context.Background(), entity.Event{Type: entity.EventBlocked, Key: "b"}); err != nil {
+		t.Errorf("Expected publish not to fail when queue is full, got %v", err)
+	}
+}
@@ -0,0 +1,26 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/entity"
+	"github.com/redis/go-redis/v9"
+)
+
+type RedisPubSubSink struct {
+	Client  *redis.Client
+	Channel string
+}
+
+func NewRedisPubSubSink(client *redis.Client, channel string) *RedisPubSubSink {
+	return &RedisPubSubSink{Client: client, Channel: channel}
+}
+
+func (r *RedisPubSubSink) Send(ctx context.Context, event entity.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return r.Client.Publish(ctx, r.Channel, payload).Err()
+}
@@ -0,0 +1,38 @@
+package notifier_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/adapter/notifier"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/entity"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisPubSubSink(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	sub := client.Subscribe(context.Background(), "rate-limiter-events")
+	defer sub.Close()
+	if _, err := sub.Receive(context.Background()); err != nil {
+		t.Fatalf("Expected subscription: %v", err)
+	}
+
+	sink := notifier.NewRedisPubSubSink(client, "rate-limiter-events")
+	if err := sink.Send(context.Background(), entity.Event{Type: entity.EventUnblocked, Key: "10.0.0.1"}); err != nil {
+		t.Fatalf("Expected publish to succeed: %v", err)
+	}
+
+	select {
+	case msg := <-sub.Channel():
+		var event entity.Event
+		if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil || event.Type != entity.EventUnblocked || event.Key != "10.0.0.1" {
+			t.Errorf("Expected unblocked event, got %s %v", msg.Payload, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected message on channel")
+	}
+}
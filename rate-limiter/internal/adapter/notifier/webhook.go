@@ -0,0 +1,104 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/entity"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/clock"
+)
+
+const SignatureHeader = "X-RateLimiter-Signature"
+
+// WebhookSink POSTs events as JSON. When Secret is set the body is signed with
+// HMAC-SHA256 and sent as "sha256=<hex>" in X-RateLimiter-Signature. Network
+// errors, 429 and 5xx responses are retried with exponential backoff.
+type WebhookSink struct {
+	URL        string
+	Secret     string
+	MaxRetries int
+	Backoff    time.Duration
+	Client     *http.Client
+	Clock      clock.Clock
+}
+
+func NewWebhookSink(url, secret string, maxRetries int) *WebhookSink {
+	return &WebhookSink{
+		URL:        url,
+		Secret:     secret,
+		MaxRetries: maxRetries,
+		Backoff:    500 * time.Millisecond,
+		Client:     &http.Client{Timeout: 5 * time.Second},
+		Clock:      clock.Real{},
+	}
+}
+
+func (w *WebhookSink) Send(ctx context.Context, event entity.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	backoff := w.Backoff
+	for attempt := 0; ; attempt++ {
+		err = w.post(ctx, body)
+		var permanent *permanentError
+		if err == nil || errors.As(err, &permanent) || attempt >= w.MaxRetries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-w.Clock.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (w *WebhookSink) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(w.Secret, body))
+	}
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return fmt.Errorf("webhook responded %d", resp.StatusCode)
+	}
+	if resp.StatusCode >= 400 {
+		return &permanentError{status: resp.StatusCode}
+	}
+	return nil
+}
+
+// Sign returns the signature receivers should compare, with hmac.Equal,
+// against the X-RateLimiter-Signature header.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+type permanentError struct {
+	status int
+}
+
+func (e *permanentError) Error() string {
+	return fmt.Sprintf("webhook rejected event with %d", e.status)
+}
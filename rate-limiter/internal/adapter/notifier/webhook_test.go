@@ -0,0 +1,92 @@
+package notifier_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/adapter/notifier"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/entity"
+)
+
+func newWebhook(url string, retries int) *notifier.WebhookSink {
+	w := notifier.NewWebhookSink(url, "s3cret", retries)
+	w.Backoff = time.Millisecond
+	return w
+}
+
+func TestWebhookSignsPayload(t *testing.T) {
+	var got entity.Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !hmac.Equal([]byte(r.Header.Get(notifier.SignatureHeader)), []byte(notifier.Sign("s3cret", body))) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.Unmarshal(body, &got)
+	}))
+	defer srv.Close()
+
+	event := entity.Event{Type: entity.EventBlocked, Key: "token:abc", At: time.Unix(10, 0).UTC()}
+	if err := newWebhook(srv.URL, 0).Send(context.Background(), event); err != nil {
+		t.Fatalf("Expected signed delivery, got %v", err)
+	}
+	if got.Key != "token:abc" || got.Type != entity.EventBlocked {
+		t.Errorf("Expected event payload, got %+v", got)
+	}
+}
+
+func TestWebhookRetriesTransientFailures(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	if err := newWebhook(srv.URL, 3).Send(context.Background(), entity.Event{Key: "k"}); err != nil {
+		t.Errorf("Expected delivery after retries, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 attempts, got %d", calls)
+	}
+}
+
+func TestWebhookGivesUp(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	if err := newWebhook(srv.URL, 2).Send(context.Background(), entity.Event{Key: "k"}); err == nil {
+		t.Error("Expected error after exhausting retries")
+	}
+	if calls != 3 {
+		t.Errorf("Expected 1 attempt plus 2 retries, got %d", calls)
+	}
+}
+
+func TestWebhookDoesNotRetryClientErrors(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	if err := newWebhook(srv.URL, 3).Send(context.Background(), entity.Event{Key: "k"}); err == nil {
+		t.Error("Expected error for rejected event")
+	}
+	if calls != 1 {
+		t.Errorf("Expected no retries on 4xx, got %d attempts", calls)
+	}
+}
@@ -3,7 +3,10 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/entity"
 )
 
 type Config struct {
@@ -12,6 +15,33 @@ type Config struct {
 	Window           time.Duration
 	BlockDuration    time.Duration
 	RedisAddr        string
+	StorageBackend   string
+	PostgresDSN      string
+	MemcachedAddrs   []string
+	UpstreamURL      string
+	UpstreamRoutes   map[string]string
+	GRPCPort         string
+	TenantSource     string
+	TenantHeader     string
+	TenantLimits     map[string]entity.TenantLimits
+	WebhookURL       string
+	WebhookSecret    string
+	WebhookRetries   int
+	EventsChannel    string
+	EventsDebounce   time.Duration
+	AdaptiveMin      int64
+	AdaptiveMax      int64
+	AdaptiveLatency  time.Duration
+	AdaptiveErrRate  float64
+	AdaptiveIncrease int64
+	AdaptiveDecrease float64
+	ShedCapacity     int64
+	PriorityHeader   string
+	PriorityTokens   map[string]entity.Priority
+	PriorityRoutes   map[string]entity.Priority
+	WaitTokens       []string
+	WaitMax          time.Duration
+	WaitQueueDepth   int64
 }
 
 func Load() *Config {
@@ -26,6 +56,59 @@ func Load() *Config {
 	if redisAddr == "" {
 		redisAddr = "localhost:6379"
 	}
+	backend := strings.ToLower(os.Getenv("STORAGE_BACKEND"))
+	if backend == "" {
+		backend = "redis"
+	}
+	memcachedAddr := os.Getenv("MEMCACHED_ADDR")
+	if memcachedAddr == "" {
+		memcachedAddr = "localhost:11211"
+	}
+	webhookRetries, err := strconv.Atoi(os.Getenv("WEBHOOK_MAX_RETRIES"))
+	if err != nil {
+		webhookRetries = 3
+	}
+	debounceSec, err := strconv.ParseInt(os.Getenv("EVENTS_DEBOUNCE_SECONDS"), 10, 64)
+	if err != nil {
+		debounceSec = 60
+	}
+	adaptiveMin, _ := strconv.ParseInt(os.Getenv("ADAPTIVE_MIN_LIMIT"), 10, 64)
+	adaptiveMax, _ := strconv.ParseInt(os.Getenv("ADAPTIVE_MAX_LIMIT"), 10, 64)
+	latencyMs, err := strconv.ParseInt(os.Getenv("ADAPTIVE_TARGET_LATENCY_MS"), 10, 64)
+	if err != nil {
+		latencyMs = 500
+	}
+	errRate, err := strconv.ParseFloat(os.Getenv("ADAPTIVE_MAX_ERROR_RATE"), 64)
+	if err != nil {
+		errRate = 0.05
+	}
+	increase, err := strconv.ParseInt(os.Getenv("ADAPTIVE_INCREASE"), 10, 64)
+	if err != nil {
+		increase = 1
+	}
+	decrease, err := strconv.ParseFloat(os.Getenv("ADAPTIVE_DECREASE_FACTOR"), 64)
+	if err != nil {
+		decrease = 0.5
+	}
+	shedCapacity, _ := strconv.ParseInt(os.Getenv("SHED_CAPACITY"), 10, 64)
+	var waitTokens []string
+	for _, token := range strings.Split(os.Getenv("WAIT_TOKENS"), ",") {
+		if token = strings.TrimSpace(token); token != "" {
+			waitTokens = append(waitTokens, token)
+		}
+	}
+	waitMs, err := strconv.ParseInt(os.Getenv("WAIT_MAX_MS"), 10, 64)
+	if err != nil {
+		waitMs = 1000
+	}
+	waitDepth, err := strconv.ParseInt(os.Getenv("WAIT_QUEUE_DEPTH"), 10, 64)
+	if err != nil {
+		waitDepth = 100
+	}
+	tenantHeader := os.Getenv("TENANT_HEADER")
+	if tenantHeader == "" {
+		tenantHeader = "X-Tenant-ID"
+	}
 
 	return &Config{
 		MaxRequests:      maxReq,
@@ -33,5 +116,84 @@ func Load() *Config {
 		Window:           time.Duration(windowSec) * time.Second,
 		BlockDuration:    time.Duration(blockSec) * time.Second,
 		RedisAddr:        redisAddr,
+		StorageBackend:   backend,
+		PostgresDSN:      os.Getenv("POSTGRES_DSN"),
+		MemcachedAddrs:   strings.Split(memcachedAddr, ","),
+		UpstreamURL:      os.Getenv("UPSTREAM_URL"),
+		UpstreamRoutes:   parseRoutes(os.Getenv("UPSTREAM_ROUTES")),
+		GRPCPort:         os.Getenv("GRPC_PORT"),
+		TenantSource:     strings.ToLower(os.Getenv("TENANT_SOURCE")),
+		TenantHeader:     tenantHeader,
+		TenantLimits:     parseTenantLimits(os.Getenv("TENANT_LIMITS")),
+		WebhookURL:       os.Getenv("WEBHOOK_URL"),
+		WebhookSecret:    os.Getenv("WEBHOOK_SECRET"),
+		WebhookRetries:   webhookRetries,
+		EventsChannel:    os.Getenv("EVENTS_REDIS_CHANNEL"),
+		EventsDebounce:   time.Duration(debounceSec) * time.Second,
+		AdaptiveMin:      adaptiveMin,
+		AdaptiveMax:      adaptiveMax,
+		AdaptiveLatency:  time.Duration(latencyMs) * time.Millisecond,
+		AdaptiveErrRate:  errRate,
+		AdaptiveIncrease: increase,
+		AdaptiveDecrease: decrease,
+		ShedCapacity:     shedCapacity,
+		PriorityHeader:   os.Getenv("PRIORITY_HEADER"),
+		PriorityTokens:   parsePriorities(os.Getenv("PRIORITY_TOKENS")),
+		PriorityRoutes:   parsePriorities(os.Getenv("PRIORITY_ROUTES")),
+		WaitTokens:       waitTokens,
+		WaitMax:          time.Duration(waitMs) * time.Millisecond,
+		WaitQueueDepth:   waitDepth,
+	}
+}
+
+// parseRoutes reads "prefix=url" pairs separated by commas, e.g.
+// "/orders=http://orders:8080,/patients=http://patients:8080".
+func parseRoutes(raw string) map[string]string {
+	routes := make(map[string]string)
+	for _, pair := range strings.Split(raw, ",") {
+		prefix, upstream, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || prefix == "" || upstream == "" {
+			continue
+		}
+		routes[strings.TrimSpace(prefix)] = strings.TrimSpace(upstream)
+	}
+	return routes
+}
+
+// parseTenantLimits reads "tenant=ip/token/total" entries separated by commas,
+// e.g. "acme=5/10/100,globex=2/4/0". Missing or zero values use the defaults.
+func parseTenantLimits(raw string) map[string]entity.TenantLimits {
+	tenants := make(map[string]entity.TenantLimits)
+	for _, pair := range strings.Split(raw, ",") {
+		tenant, spec, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || tenant == "" {
+			continue
+		}
+		var values [3]int64
+		for i, v := range strings.SplitN(spec, "/", 3) {
+			values[i], _ = strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		}
+		tenants[strings.TrimSpace(tenant)] = entity.TenantLimits{
+			MaxRequests:      values[0],
+			MaxTokenRequests: values[1],
+			MaxTotal:         values[2],
+		}
+	}
+	return tenants
+}
+
+// parsePriorities reads "key=class" entries separated by commas, e.g.
+// "/admin=critical,/public=low". Unknown classes are ignored.
+func parsePriorities(raw string) map[string]entity.Priority {
+	priorities := make(map[string]entity.Priority)
+	for _, pair := range strings.Split(raw, ",") {
+		key, class, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || key == "" {
+			continue
+		}
+		if priority, ok := entity.ParsePriority(strings.TrimSpace(class)); ok {
+			priorities[strings.TrimSpace(key)] = priority
+		}
 	}
+	return priorities
 }
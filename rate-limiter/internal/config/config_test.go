@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/jpfigueredo/rate-limiter-challenge/internal/config"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/entity"
 )
 
 func TestLoadAllEnvs(t *testing.T) {
@@ -14,10 +15,11 @@ func TestLoadAllEnvs(t *testing.T) {
 	os.Setenv("WINDOW_SECONDS", "3")
 	os.Setenv("BLOCK_DURATION_SECONDS", "600")
 	os.Setenv("REDIS_ADDR", "test:6379")
+	os.Setenv("GRPC_PORT", "8081")
 	defer os.Clearenv()
 
 	cfg := config.Load()
-	if cfg.MaxRequests != 3 || cfg.MaxTokenRequests != 7 || cfg.Window != 3*time.Second || cfg.BlockDuration != 600*time.Second || cfg.RedisAddr != "test:6379" {
+	if cfg.MaxRequests != 3 || cfg.MaxTokenRequests != 7 || cfg.Window != 3*time.Second || cfg.BlockDuration != 600*time.Second || cfg.RedisAddr != "test:6379" || cfg.GRPCPort != "8081" {
 		t.Error("Expected all envs loaded")
 	}
 }
@@ -27,7 +29,115 @@ func TestLoadPartialEnvs(t *testing.T) {
 	defer os.Clearenv()
 
 	cfg := config.Load()
-	if cfg.MaxRequests != 4 || cfg.Window != time.Second || cfg.RedisAddr != "localhost:6379" {
+	if cfg.MaxRequests != 4 || cfg.Window != time.Second || cfg.RedisAddr != "localhost:6379" || cfg.StorageBackend != "redis" {
 		t.Error("Expected partial envs with defaults")
 	}
 }
+
+func TestLoadUpstreams(t *testing.T) {
+	os.Setenv("UPSTREAM_URL", "http://backend:9000")
+	os.Setenv("UPSTREAM_ROUTES", "/orders=http://orders:8080, /patients=http://patients:8081,invalid")
+	defer os.Clearenv()
+
+	cfg := config.Load()
+	if cfg.UpstreamURL != "http://backend:9000" {
+		t.Errorf("Expected upstream url, got %s", cfg.UpstreamURL)
+	}
+	if len(cfg.UpstreamRoutes) != 2 || cfg.UpstreamRoutes["/orders"] != "http://orders:8080" || cfg.UpstreamRoutes["/patients"] != "http://patients:8081" {
+		t.Errorf("Expected two upstream routes, got %v", cfg.UpstreamRoutes)
+	}
+}
+
+func TestLoadTenants(t *testing.T) {
+	os.Setenv("TENANT_SOURCE", "Header")
+	os.Setenv("TENANT_LIMITS", "acme=5/10/100, globex=2,broken")
+	defer os.Clearenv()
+
+	cfg := config.Load()
+	if cfg.TenantSource != "header" || cfg.TenantHeader != "X-Tenant-ID" {
+		t.Errorf("Expected header tenant source with default header, got %s %s", cfg.TenantSource, cfg.TenantHeader)
+	}
+	if len(cfg.TenantLimits) != 2 {
+		t.Fatalf("Expected two tenants, got %v", cfg.TenantLimits)
+	}
+	acme := cfg.TenantLimits["acme"]
+	if acme.MaxRequests != 5 || acme.MaxTokenRequests != 10 || acme.MaxTotal != 100 {
+		t.Errorf("Expected acme limits, got %+v", acme)
+	}
+	globex := cfg.TenantLimits["globex"]
+	if globex.MaxRequests != 2 || globex.MaxTokenRequests != 0 || globex.MaxTotal != 0 {
+		t.Errorf("Expected globex partial limits, got %+v", globex)
+	}
+}
+
+func TestLoadStorageBackend(t *testing.T) {
+	os.Setenv("STORAGE_BACKEND", "Memcached")
+	os.Setenv("MEMCACHED_ADDR", "mc1:11211,mc2:11211")
+	os.Setenv("POSTGRES_DSN", "postgres://localhost/limiter")
+	defer os.Clearenv()
+
+	cfg := config.Load()
+	if cfg.StorageBackend != "memcached" || len(cfg.MemcachedAddrs) != 2 || cfg.MemcachedAddrs[1] != "mc2:11211" || cfg.PostgresDSN != "postgres://localhost/limiter" {
+		t.Errorf("Expected storage backend settings, got %s %v %s", cfg.StorageBackend, cfg.MemcachedAddrs, cfg.PostgresDSN)
+	}
+}
+
+func TestLoadEvents(t *testing.T) {
+	os.Setenv("WEBHOOK_URL", "https://hooks.example.com/limiter")
+	os.Setenv("WEBHOOK_SECRET", "s3cret")
+	os.Setenv("EVENTS_REDIS_CHANNEL", "limiter-events")
+	os.Setenv("EVENTS_DEBOUNCE_SECONDS", "0")
+	defer os.Clearenv()
+
+	cfg := config.Load()
+	if cfg.WebhookURL != "https://hooks.example.com/limiter" || cfg.WebhookSecret != "s3cret" || cfg.WebhookRetries != 3 || cfg.EventsChannel != "limiter-events" || cfg.EventsDebounce != 0 {
+		t.Errorf("Expected event settings, got %+v", cfg)
+	}
+}
+
+func TestLoadAdaptive(t *testing.T) {
+	os.Setenv("ADAPTIVE_MIN_LIMIT", "10")
+	os.Setenv("ADAPTIVE_MAX_LIMIT", "500")
+	os.Setenv("ADAPTIVE_TARGET_LATENCY_MS", "250")
+	defer os.Clearenv()
+
+	cfg := config.Load()
+	if cfg.AdaptiveMin != 10 || cfg.AdaptiveMax != 500 || cfg.AdaptiveLatency != 250*time.Millisecond {
+		t.Errorf("Expected adaptive bounds and target, got %+v", cfg)
+	}
+	if cfg.AdaptiveErrRate != 0.05 || cfg.AdaptiveIncrease != 1 || cfg.AdaptiveDecrease != 0.5 {
+		t.Errorf("Expected adaptive defaults, got %+v", cfg)
+	}
+}
+
+func TestLoadPriorities(t *testing.T) {
+	os.Setenv("SHED_CAPACITY", "1000")
+	os.Setenv("PRIORITY_TOKENS", "gold=critical, silver=High,bronze=urgent")
+	os.Setenv("PRIORITY_ROUTES", "/admin=high,/public=low")
+	defer os.Clearenv()
+
+	cfg := config.Load()
+	if cfg.ShedCapacity != 1000 || cfg.PriorityHeader != "" {
+		t.Errorf("Expected shedding settings without priority header, got %d %s", cfg.ShedCapacity, cfg.PriorityHeader)
+	}
+	if len(cfg.PriorityTokens) != 2 || cfg.PriorityTokens["gold"] != entity.PriorityCritical || cfg.PriorityTokens["silver"] != entity.PriorityHigh {
+		t.Errorf("Expected two token priorities, got %v", cfg.PriorityTokens)
+	}
+	if len(cfg.PriorityRoutes) != 2 || cfg.PriorityRoutes["/public"] != entity.PriorityLow {
+		t.Errorf("Expected two route priorities, got %v", cfg.PriorityRoutes)
+	}
+}
+
+func TestLoadWaitQueue(t *testing.T) {
+	os.Setenv("WAIT_TOKENS", "billing, reports,")
+	os.Setenv("WAIT_MAX_MS", "250")
+	defer os.Clearenv()
+
+	cfg := config.Load()
+	if len(cfg.WaitTokens) != 2 || cfg.WaitTokens[1] != "reports" {
+		t.Errorf("Expected two wait tokens, got %v", cfg.WaitTokens)
+	}
+	if cfg.WaitMax != 250*time.Millisecond || cfg.WaitQueueDepth != 100 {
+		t.Errorf("Expected wait settings with default depth, got %v %d", cfg.WaitMax, cfg.WaitQueueDepth)
+	}
+}
@@ -0,0 +1,17 @@
+package entity
+
+import "time"
+
+type EventType string
+
+const (
+	EventBlocked   EventType = "blocked"
+	EventUnblocked EventType = "unblocked"
+)
+
+type Event struct {
+	Type         EventType `json:"type"`
+	Key          string    `json:"key"`
+	At           time.Time `json:"at"`
+	BlockedUntil time.Time `json:"blocked_until"`
+}
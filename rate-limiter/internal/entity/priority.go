@@ -0,0 +1,36 @@
+package entity
+
+import "strings"
+
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+	PriorityCritical
+)
+
+var priorityNames = map[Priority]string{
+	PriorityLow:      "low",
+	PriorityNormal:   "normal",
+	PriorityHigh:     "high",
+	PriorityCritical: "critical",
+}
+
+func (p Priority) String() string {
+	if name, ok := priorityNames[p]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+func ParsePriority(s string) (Priority, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for p, name := range priorityNames {
+		if name == s {
+			return p, true
+		}
+	}
+	return PriorityLow, false
+}
@@ -7,3 +7,12 @@ type RateLimit struct {
 	Count        int64
 	BlockedUntil time.Time
 }
+
+// TenantLimits overrides the default limits for one tenant. Zero values fall
+// back to the defaults; MaxTotal caps all clients of the tenant together and
+// is disabled when zero.
+type TenantLimits struct {
+	MaxRequests      int64
+	MaxTokenRequests int64
+	MaxTotal         int64
+}
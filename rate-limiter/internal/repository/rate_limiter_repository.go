@@ -9,7 +9,14 @@ import (
 
 type RateLimiterRepository interface {
 	Increment(ctx context.Context, key string, window time.Duration) (int64, error)
+	// Block with a zero or negative duration does nothing, in every backend.
 	Block(ctx context.Context, key string, blockDuration time.Duration) error
 	IsBlocked(ctx context.Context, key string) (bool, error)
 	GetState(ctx context.Context, key string) (*entity.RateLimit, error)
 }
+
+// BatchIncrementer is implemented by repositories that can count n hits in a
+// single call; otherwise callers fall back to n calls to Increment.
+type BatchIncrementer interface {
+	IncrementBy(ctx context.Context, key string, n int64, window time.Duration) (int64, error)
+}
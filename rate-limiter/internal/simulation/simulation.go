@@ -0,0 +1,120 @@
+package simulation
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/usecase"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/clock"
+)
+
+// Event is a single synthetic request, At is the offset from the start of
+// the simulation in virtual time.
+type Event struct {
+	At     time.Duration
+	Tenant string
+	IP     string
+	Token  string
+}
+
+type Trace []Event
+
+// Steady spreads rps requests per second evenly over d.
+func Steady(ip, token string, rps int, d time.Duration) Trace {
+	interval := time.Second / time.Duration(rps)
+	var t Trace
+	for at := time.Duration(0); at < d; at += interval {
+		t = append(t, Event{At: at, IP: ip, Token: token})
+	}
+	return t
+}
+
+// Burst sends n requests at the same instant.
+func Burst(ip, token string, n int, at time.Duration) Trace {
+	t := make(Trace, n)
+	for i := range t {
+		t[i] = Event{At: at, IP: ip, Token: token}
+	}
+	return t
+}
+
+// WithTenant returns a copy of trace with every event sent by tenant.
+func WithTenant(tenant string, trace Trace) Trace {
+	t := make(Trace, len(trace))
+	for i, e := range trace {
+		e.Tenant = tenant
+		t[i] = e
+	}
+	return t
+}
+
+// Merge interleaves traces by time, keeping the relative order of events that
+// share an instant.
+func Merge(traces ...Trace) Trace {
+	var merged Trace
+	for _, t := range traces {
+		merged = append(merged, t...)
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].At < merged[j].At
+	})
+	return merged
+}
+
+type Tally struct {
+	Allowed int
+	Denied  int
+}
+
+type Result struct {
+	Tally
+	PerClient map[string]Tally
+}
+
+// Runner replays traces against a use case. Advance must move every clock the
+// use case and its backend depend on, e.g. the fake clock and miniredis.
+type Runner struct {
+	UseCase *usecase.RateLimiterUseCase
+	Clock   *clock.Fake
+	Advance func(d time.Duration)
+}
+
+func NewRunner(uc *usecase.RateLimiterUseCase, clk *clock.Fake) *Runner {
+	uc.Clock = clk
+	return &Runner{UseCase: uc, Clock: clk, Advance: clk.Advance}
+}
+
+func (r *Runner) Run(ctx context.Context, trace Trace) (Result, error) {
+	res := Result{PerClient: make(map[string]Tally)}
+	var elapsed time.Duration
+	for _, e := range Merge(trace) {
+		if e.At > elapsed {
+			r.Advance(e.At - elapsed)
+			elapsed = e.At
+		}
+
+		allowed, err := r.UseCase.CheckAndIncrementForTenant(ctx, e.Tenant, e.IP, e.Token)
+		if err != nil {
+			return res, err
+		}
+
+		client := e.IP
+		if e.Token != "" {
+			client = "token:" + e.Token
+		}
+		if e.Tenant != "" {
+			client = e.Tenant + "/" + client
+		}
+		tally := res.PerClient[client]
+		if allowed {
+			res.Allowed++
+			tally.Allowed++
+		} else {
+			res.Denied++
+			tally.Denied++
+		}
+		res.PerClient[client] = tally
+	}
+	return res, nil
+}
@@ -0,0 +1,166 @@
+package simulation_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/entity"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/repository"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/simulation"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/usecase"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/clock"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/storage"
+	"github.com/redis/go-redis/v9"
+)
+
+type backend struct {
+	name string
+	new  func(t *testing.T, clk *clock.Fake) (repository.RateLimiterRepository, func(time.Duration))
+}
+
+var backends = []backend{
+	{
+		name: "memory",
+		new: func(t *testing.T, clk *clock.Fake) (repository.RateLimiterRepository, func(time.Duration)) {
+			return storage.NewMemoryRateLimiter(clk), clk.Advance
+		},
+	},
+	{
+		name: "redis",
+		new: func(t *testing.T, clk *clock.Fake) (repository.RateLimiterRepository, func(time.Duration)) {
+			mr := miniredis.RunT(t)
+			repo := &storage.RedisRateLimiter{Client: redis.NewClient(&redis.Options{Addr: mr.Addr()}), Clock: clk}
+			return repo, func(d time.Duration) {
+				clk.Advance(d)
+				mr.FastForward(d)
+			}
+		},
+	},
+}
+
+func newRunner(t *testing.T, b backend) *simulation.Runner {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	repo, advance := b.new(t, clk)
+	uc := usecase.NewRateLimiterUseCase(repo, 5, 10, time.Second, 5*time.Second)
+	runner := simulation.NewRunner(uc, clk)
+	runner.Advance = advance
+	return runner
+}
+
+func TestSimulationScenarios(t *testing.T) {
+	scenarios := []struct {
+		name    string
+		trace   simulation.Trace
+		allowed int
+		denied  int
+	}{
+		{"steady under IP limit", simulation.Steady("10.0.0.1", "", 5, 10*time.Second), 50, 0},
+		// 6th request at 500ms blocks until 5.5s, the next window allows 5
+		// more and blocks again at 6s until the end of the trace.
+		{"steady over IP limit", simulation.Steady("10.0.0.2", "", 10, 10*time.Second), 10, 90},
+		{"token overrides IP limit", simulation.Steady("10.0.0.3", "abc", 8, 5*time.Second), 40, 0},
+		{"bursts around block expiry", simulation.Merge(
+			simulation.Burst("10.0.0.4", "", 20, 0),
+			simulation.Burst("10.0.0.4", "", 20, 4*time.Second),
+			simulation.Burst("10.0.0.4", "", 20, 5*time.Second),
+		), 10, 50},
+	}
+
+	for _, b := range backends {
+		for _, sc := range scenarios {
+			t.Run(b.name+"/"+sc.name, func(t *testing.T) {
+				res, err := newRunner(t, b).Run(context.Background(), sc.trace)
+				if err != nil {
+					t.Fatalf("Expected simulation to run: %v", err)
+				}
+				if res.Allowed != sc.allowed || res.Denied != sc.denied {
+					t.Errorf("Expected %d allowed and %d denied, got %d and %d", sc.allowed, sc.denied, res.Allowed, res.Denied)
+				}
+			})
+		}
+	}
+}
+
+func TestSimulationClientsAreIsolated(t *testing.T) {
+	trace := simulation.Merge(
+		simulation.Steady("10.0.0.1", "", 10, 3*time.Second),
+		simulation.Steady("10.0.0.1", "abc", 10, 3*time.Second),
+		simulation.Steady("10.0.0.2", "", 4, 3*time.Second),
+	)
+
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			res, err := newRunner(t, b).Run(context.Background(), trace)
+			if err != nil {
+				t.Fatalf("Expected simulation to run: %v", err)
+			}
+			expected := map[string]simulation.Tally{
+				"10.0.0.1":  {Allowed: 5, Denied: 25},
+				"token:abc": {Allowed: 30, Denied: 0},
+				"10.0.0.2":  {Allowed: 12, Denied: 0},
+			}
+			for client, tally := range expected {
+				if res.PerClient[client] != tally {
+					t.Errorf("Expected %+v for %s, got %+v", tally, client, res.PerClient[client])
+				}
+			}
+		})
+	}
+}
+
+func TestSimulationBlockedForUsesVirtualTime(t *testing.T) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			runner := newRunner(t, b)
+			if _, err := runner.Run(context.Background(), simulation.Burst("10.0.0.9", "", 6, 0)); err != nil {
+				t.Fatalf("Expected simulation to run: %v", err)
+			}
+			runner.Advance(2 * time.Second)
+
+			d, err := runner.UseCase.BlockedFor(context.Background(), "10.0.0.9")
+			if err != nil || d != 3*time.Second {
+				t.Errorf("Expected 3s of block left, got %v %v", d, err)
+			}
+		})
+	}
+}
+
+func TestSimulationTenants(t *testing.T) {
+	acmeIPs := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5"}
+	var traces []simulation.Trace
+	for _, ip := range acmeIPs {
+		traces = append(traces, simulation.WithTenant("acme", simulation.Steady(ip, "", 5, 2*time.Second)))
+	}
+	traces = append(traces, simulation.WithTenant("globex", simulation.Steady("10.0.0.1", "", 10, 2*time.Second)))
+	trace := simulation.Merge(traces...)
+
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			runner := newRunner(t, b)
+			runner.UseCase.Tenants = map[string]entity.TenantLimits{
+				"acme":   {MaxRequests: 8, MaxTotal: 20},
+				"globex": {MaxRequests: 3},
+			}
+			res, err := runner.Run(context.Background(), trace)
+			if err != nil {
+				t.Fatalf("Expected simulation to run: %v", err)
+			}
+
+			// Each acme client stays under its own limit of 8 per window but the
+			// tenant-wide cap of 20 lets only 20 of the 25 requests through in
+			// each window.
+			var acmeAllowed int
+			for _, ip := range acmeIPs {
+				acmeAllowed += res.PerClient["acme/"+ip].Allowed
+			}
+			if acmeAllowed != 40 {
+				t.Errorf("Expected acme aggregate cap to allow 40, got %d", acmeAllowed)
+			}
+			if got := res.PerClient["globex/10.0.0.1"]; got.Allowed != 3 || got.Denied != 17 {
+				t.Errorf("Expected globex client limited to 3 and then blocked, got %+v", got)
+			}
+		})
+	}
+}
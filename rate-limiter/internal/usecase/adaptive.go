@@ -0,0 +1,123 @@
+package usecase
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/clock"
+)
+
+// AdaptiveLimiter is an AIMD controller for the global request limit. Every
+// Interval it looks at the upstream responses observed since the last
+// adjustment: if the mean latency is above TargetLatency or the 5xx rate is
+// above MaxErrorRate the limit is multiplied by DecreaseFactor, otherwise it
+// grows by Increase, as it also does for every interval without traffic. The
+// limit always stays within [Floor, Ceiling], and never drops below 1.
+type AdaptiveLimiter struct {
+	Floor          int64
+	Ceiling        int64
+	TargetLatency  time.Duration
+	MaxErrorRate   float64
+	Increase       int64
+	DecreaseFactor float64
+	Interval       time.Duration
+	Clock          clock.Clock
+
+	mu           sync.Mutex
+	limit        int64
+	windowStart  time.Time
+	samples      int64
+	errors       int64
+	totalLatency time.Duration
+	lastLatency  time.Duration
+	lastErrRate  float64
+}
+
+type AdaptiveStats struct {
+	Limit       int64
+	MeanLatency time.Duration
+	ErrorRate   float64
+}
+
+func NewAdaptiveLimiter(floor, ceiling int64, targetLatency time.Duration, maxErrorRate float64) *AdaptiveLimiter {
+	return &AdaptiveLimiter{
+		Floor:          floor,
+		Ceiling:        ceiling,
+		TargetLatency:  targetLatency,
+		MaxErrorRate:   maxErrorRate,
+		Increase:       1,
+		DecreaseFactor: 0.5,
+		Interval:       time.Second,
+		Clock:          clock.Real{},
+		limit:          ceiling,
+	}
+}
+
+func (a *AdaptiveLimiter) Observe(latency time.Duration, status int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.adjust()
+	a.samples++
+	a.totalLatency += latency
+	if status >= 500 {
+		a.errors++
+	}
+}
+
+func (a *AdaptiveLimiter) Limit() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.adjust()
+	return a.limit
+}
+
+// Stats reports the current limit and the measurements of the last completed
+// interval.
+func (a *AdaptiveLimiter) Stats() AdaptiveStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.adjust()
+	return AdaptiveStats{Limit: a.limit, MeanLatency: a.lastLatency, ErrorRate: a.lastErrRate}
+}
+
+func (a *AdaptiveLimiter) adjust() {
+	now := a.Clock.Now()
+	if a.windowStart.IsZero() {
+		a.windowStart = now
+		return
+	}
+	if now.Sub(a.windowStart) < a.Interval {
+		return
+	}
+	// Intervals without traffic count as healthy. Near the floor most requests
+	// may be rejected before reaching the upstream, so without this the limit
+	// could never recover.
+	idle := int64(now.Sub(a.windowStart) / a.Interval)
+	a.windowStart = now
+
+	if a.samples > 0 {
+		idle--
+		a.lastLatency = a.totalLatency / time.Duration(a.samples)
+		a.lastErrRate = float64(a.errors) / float64(a.samples)
+		a.samples, a.errors, a.totalLatency = 0, 0, 0
+
+		if a.lastLatency > a.TargetLatency || a.lastErrRate > a.MaxErrorRate {
+			a.limit = int64(float64(a.limit) * a.DecreaseFactor)
+		} else {
+			a.limit += a.Increase
+		}
+	}
+	a.limit += idle * a.Increase
+
+	// A limit of 0 would reject every request, so the floor is at least 1.
+	floor := a.Floor
+	if floor < 1 {
+		floor = 1
+	}
+	if a.limit < floor {
+		a.limit = floor
+	}
+	if a.limit > a.Ceiling {
+		a.limit = a.Ceiling
+	}
+}
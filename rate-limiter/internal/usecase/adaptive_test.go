@@ -0,0 +1,138 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/usecase"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/clock"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/storage"
+)
+
+func newAdaptive(clk *clock.Fake) *usecase.AdaptiveLimiter {
+	a := usecase.NewAdaptiveLimiter(10, 100, 200*time.Millisecond, 0.1)
+	a.Clock = clk
+	a.Increase = 5
+	a.Limit()
+	return a
+}
+
+func TestAdaptiveDecreasesOnLatency(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	a := newAdaptive(clk)
+
+	a.Observe(500*time.Millisecond, 200)
+	a.Observe(300*time.Millisecond, 200)
+	clk.Advance(time.Second)
+
+	stats := a.Stats()
+	if stats.Limit != 50 || stats.MeanLatency != 400*time.Millisecond {
+		t.Errorf("Expected limit halved on slow upstream, got %+v", stats)
+	}
+}
+
+func TestAdaptiveDecreasesOnErrorsDownToFloor(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	a := newAdaptive(clk)
+
+	for i := 0; i < 5; i++ {
+		a.Observe(10*time.Millisecond, 503)
+		a.Observe(10*time.Millisecond, 200)
+		clk.Advance(time.Second)
+	}
+
+	stats := a.Stats()
+	if stats.Limit != 10 || stats.ErrorRate != 0.5 {
+		t.Errorf("Expected limit at floor on errors, got %+v", stats)
+	}
+}
+
+func TestAdaptiveIncreasesUpToCeiling(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	a := newAdaptive(clk)
+	a.Observe(time.Second, 200)
+	clk.Advance(time.Second)
+	if a.Limit() != 50 {
+		t.Fatalf("Expected limit 50 after slow interval, got %d", a.Limit())
+	}
+
+	for i := 0; i < 20; i++ {
+		a.Observe(10*time.Millisecond, 200)
+		clk.Advance(time.Second)
+	}
+	if a.Limit() != 100 {
+		t.Errorf("Expected additive increase capped at ceiling, got %d", a.Limit())
+	}
+}
+
+func TestAdaptiveGrowsWithoutTraffic(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	a := newAdaptive(clk)
+	a.Observe(time.Second, 200)
+	clk.Advance(time.Second)
+	clk.Advance(2 * time.Second)
+	if a.Limit() != 60 {
+		t.Errorf("Expected the slow interval halved and two idle intervals added, got %d", a.Limit())
+	}
+}
+
+func TestAdaptiveRecoversFromZeroFloor(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	a := usecase.NewAdaptiveLimiter(0, 10, 200*time.Millisecond, 0.1)
+	a.Clock = clk
+	a.Limit()
+	for i := 0; i < 5; i++ {
+		a.Observe(10*time.Millisecond, 503)
+		clk.Advance(time.Second)
+	}
+	if a.Limit() != 1 {
+		t.Fatalf("Expected the limit to stop at 1 with a zero floor, got %d", a.Limit())
+	}
+
+	uc := usecase.NewRateLimiterUseCase(storage.NewMemoryRateLimiter(clk), 100, 100, time.Second, time.Minute)
+	uc.Clock = clk
+	uc.Adaptive = a
+	ctx := context.Background()
+	if allowed, _ := uc.CheckAndIncrement(ctx, "10.0.0.1", ""); !allowed {
+		t.Fatal("Expected one request allowed at the floor")
+	}
+	if allowed, _ := uc.CheckAndIncrement(ctx, "10.0.0.2", ""); allowed {
+		t.Fatal("Expected the second request rejected at the floor")
+	}
+
+	// Rejected requests never reach the upstream, so only idle intervals
+	// can raise the limit again.
+	clk.Advance(3 * time.Second)
+	if a.Limit() != 4 {
+		t.Fatalf("Expected idle intervals to raise the limit to 4, got %d", a.Limit())
+	}
+	for i := 0; i < 4; i++ {
+		if allowed, _ := uc.CheckAndIncrement(ctx, "10.0.0.3", ""); !allowed {
+			t.Errorf("Expected request %d allowed after recovery", i+1)
+		}
+	}
+}
+
+func TestCheckAndIncrementAdaptiveGlobalLimit(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	uc := usecase.NewRateLimiterUseCase(storage.NewMemoryRateLimiter(clk), 100, 100, time.Second, time.Minute)
+	uc.Clock = clk
+	uc.Adaptive = usecase.NewAdaptiveLimiter(1, 3, time.Second, 0.5)
+	uc.Adaptive.Clock = clk
+
+	var allowed int
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5"} {
+		if ok, err := uc.CheckAndIncrement(context.Background(), ip, ""); err == nil && ok {
+			allowed++
+		}
+	}
+	if allowed != 3 {
+		t.Errorf("Expected global adaptive limit to allow 3 across clients, got %d", allowed)
+	}
+
+	clk.Advance(time.Second)
+	if ok, _ := uc.CheckAndIncrement(context.Background(), "10.0.0.5", ""); !ok {
+		t.Error("Expected denied clients not to be blocked by the global limit")
+	}
+}
@@ -0,0 +1,77 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/entity"
+)
+
+type EventPublisher interface {
+	Publish(ctx context.Context, event entity.Event) error
+}
+
+// blockTracker remembers the keys this instance blocked so it can announce
+// when they are released; storage backends expire blocks silently.
+type blockTracker struct {
+	mu      sync.Mutex
+	blocked map[string]time.Time
+}
+
+func (t *blockTracker) add(key string, until time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.blocked == nil {
+		t.blocked = make(map[string]time.Time)
+	}
+	t.blocked[key] = until
+}
+
+func (t *blockTracker) expired(now time.Time) map[string]time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	released := make(map[string]time.Time)
+	for key, until := range t.blocked {
+		if !now.Before(until) {
+			released[key] = until
+			delete(t.blocked, key)
+		}
+	}
+	return released
+}
+
+// publishBlocked announces a block; with no BlockDuration nothing was
+// blocked, so there is nothing to announce or release later.
+func (uc *RateLimiterUseCase) publishBlocked(ctx context.Context, key string) {
+	if uc.Events == nil || uc.BlockDuration <= 0 {
+		return
+	}
+	now := uc.Clock.Now()
+	until := now.Add(uc.BlockDuration)
+	uc.blocks.add(key, until)
+	_ = uc.Events.Publish(ctx, entity.Event{Type: entity.EventBlocked, Key: key, At: now, BlockedUntil: until})
+}
+
+// ReleaseExpiredBlocks publishes an unblocked event for every key whose block
+// has run out. It is meant to be called periodically, see RunEventSweeper.
+func (uc *RateLimiterUseCase) ReleaseExpiredBlocks(ctx context.Context) {
+	if uc.Events == nil {
+		return
+	}
+	now := uc.Clock.Now()
+	for key, until := range uc.blocks.expired(now) {
+		_ = uc.Events.Publish(ctx, entity.Event{Type: entity.EventUnblocked, Key: key, At: now, BlockedUntil: until})
+	}
+}
+
+func (uc *RateLimiterUseCase) RunEventSweeper(ctx context.Context, interval time.Duration) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-uc.Clock.After(interval):
+			uc.ReleaseExpiredBlocks(ctx)
+		}
+	}
+}
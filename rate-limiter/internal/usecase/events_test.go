@@ -0,0 +1,98 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/entity"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/usecase"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/clock"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/storage"
+)
+
+type recordingPublisher struct {
+	events []entity.Event
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event entity.Event) error {
+	p.events = append(p.events, event)
+	return nil
+}
+
+func TestBlockAndUnblockEvents(t *testing.T) {
+	start := time.Unix(0, 0)
+	clk := clock.NewFake(start)
+	pub := &recordingPublisher{}
+	uc := usecase.NewRateLimiterUseCase(storage.NewMemoryRateLimiter(clk), 1, 10, time.Second, 5*time.Second)
+	uc.Clock = clk
+	uc.Events = pub
+
+	for i := 0; i < 3; i++ {
+		_, _ = uc.CheckAndIncrement(context.Background(), "10.0.0.1", "")
+	}
+	if len(pub.events) != 1 || pub.events[0].Type != entity.EventBlocked || pub.events[0].Key != "10.0.0.1" || !pub.events[0].BlockedUntil.Equal(start.Add(5*time.Second)) {
+		t.Fatalf("Expected a single blocked event, got %+v", pub.events)
+	}
+
+	clk.Advance(4 * time.Second)
+	uc.ReleaseExpiredBlocks(context.Background())
+	if len(pub.events) != 1 {
+		t.Fatalf("Expected no unblock before the block expires, got %+v", pub.events)
+	}
+
+	clk.Advance(time.Second)
+	uc.ReleaseExpiredBlocks(context.Background())
+	uc.ReleaseExpiredBlocks(context.Background())
+	if len(pub.events) != 2 || pub.events[1].Type != entity.EventUnblocked || pub.events[1].Key != "10.0.0.1" {
+		t.Errorf("Expected one unblocked event, got %+v", pub.events)
+	}
+}
+
+func TestZeroBlockDurationPublishesNoEvents(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	pub := &recordingPublisher{}
+	uc := usecase.NewRateLimiterUseCase(storage.NewMemoryRateLimiter(clk), 1, 10, time.Second, 0)
+	uc.Clock = clk
+	uc.Events = pub
+
+	for i := 0; i < 3; i++ {
+		if allowed, _ := uc.CheckAndIncrement(context.Background(), "10.0.0.1", ""); allowed != (i == 0) {
+			t.Fatalf("Expected only the first request allowed, got %v for request %d", allowed, i+1)
+		}
+	}
+	clk.Advance(time.Second)
+	uc.ReleaseExpiredBlocks(context.Background())
+	if len(pub.events) != 0 {
+		t.Errorf("Expected no events without a block duration, got %+v", pub.events)
+	}
+}
+
+func TestEventSweeperReleasesOnTick(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	pub := &recordingPublisher{}
+	uc := usecase.NewRateLimiterUseCase(storage.NewMemoryRateLimiter(clk), 0, 10, time.Second, time.Second)
+	uc.Clock = clk
+	uc.Events = pub
+	_, _ = uc.CheckAndIncrement(context.Background(), "10.0.0.1", "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		uc.RunEventSweeper(ctx, time.Second)
+		close(done)
+	}()
+	for clk.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clk.Advance(time.Second)
+	for clk.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if len(pub.events) != 2 || pub.events[1].Type != entity.EventUnblocked {
+		t.Errorf("Expected sweeper to publish unblock, got %+v", pub.events)
+	}
+}
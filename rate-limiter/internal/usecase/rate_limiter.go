@@ -6,6 +6,7 @@ import (
 
 	"github.com/jpfigueredo/rate-limiter-challenge/internal/entity"
 	"github.com/jpfigueredo/rate-limiter-challenge/internal/repository"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/clock"
 )
 
 type RateLimiterUseCase struct {
@@ -14,6 +15,14 @@ type RateLimiterUseCase struct {
 	MaxTokenReqs  int64
 	Window        time.Duration
 	BlockDuration time.Duration
+	Clock         clock.Clock
+	Tenants       map[string]entity.TenantLimits
+	Events        EventPublisher
+	Adaptive      *AdaptiveLimiter
+	Shedder       *LoadShedder
+	Queue         *WaitQueue
+
+	blocks blockTracker
 }
 
 func NewRateLimiterUseCase(repo repository.RateLimiterRepository, maxReq int64, maxToken int64, window, block time.Duration) *RateLimiterUseCase {
@@ -23,17 +32,90 @@ func NewRateLimiterUseCase(repo repository.RateLimiterRepository, maxReq int64,
 		MaxTokenReqs:  maxToken,
 		Window:        window,
 		BlockDuration: block,
+		Clock:         clock.Real{},
 	}
 }
 
 func (uc *RateLimiterUseCase) CheckAndIncrement(ctx context.Context, ip, token string) (bool, error) {
+	return uc.CheckAndIncrementForTenant(ctx, "", ip, token)
+}
+
+// CheckAndIncrementForTenant namespaces the client key by tenant and applies
+// the tenant limits, including the tenant-wide cap shared by all its clients.
+func (uc *RateLimiterUseCase) CheckAndIncrementForTenant(ctx context.Context, tenant, ip, token string) (bool, error) {
+	return uc.checkTenant(ctx, tenant, ip, token, true)
+}
+
+func (uc *RateLimiterUseCase) checkTenant(ctx context.Context, tenant, ip, token string, block bool) (bool, error) {
+	key, max := uc.clientKey(tenant, ip, token)
+	allowed, err := uc.checkKey(ctx, key, max, uc.Window, 1, block)
+	if err != nil || !allowed {
+		return allowed, err
+	}
+
+	if limits := uc.Tenants[tenant]; tenant != "" && limits.MaxTotal > 0 {
+		count, err := uc.Repo.Increment(ctx, tenantPrefix(tenant)+"total", uc.Window)
+		if err != nil || count > limits.MaxTotal {
+			return false, err
+		}
+	}
+
+	if uc.Adaptive != nil {
+		count, err := uc.Repo.Increment(ctx, globalKey, uc.Window)
+		if err != nil || count > uc.Adaptive.Limit() {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// globalKey counts every request that passed the per-client checks, against
+// the adaptive global limit.
+const globalKey = "global"
+
+// clientKey namespaces only tenants configured in Tenants. Tenant values come
+// from the request, so an unknown one falls back to the plain IP or token key;
+// otherwise rotating it would give the client a fresh counter every time.
+func (uc *RateLimiterUseCase) clientKey(tenant, ip, token string) (string, int64) {
+	limits, known := uc.Tenants[tenant]
 	key := ip
 	max := uc.MaxRequests
+	if limits.MaxRequests > 0 {
+		max = limits.MaxRequests
+	}
 	if token != "" {
 		key = "token:" + token
 		max = uc.MaxTokenReqs
+		if limits.MaxTokenRequests > 0 {
+			max = limits.MaxTokenRequests
+		}
+	}
+	if tenant != "" && known {
+		key = tenantPrefix(tenant) + key
+	}
+	return key, max
+}
+
+func tenantPrefix(tenant string) string {
+	return "tenant:" + tenant + ":"
+}
+
+// CheckKey applies the limit directly to an already resolved key, for callers
+// such as the external rate-limit services that build keys themselves.
+func (uc *RateLimiterUseCase) CheckKey(ctx context.Context, key string, max int64, window time.Duration) (bool, error) {
+	return uc.checkKey(ctx, key, max, window, 1, true)
+}
+
+// CheckKeyHits is CheckKey for a request that counts as hits requests, such
+// as Envoy descriptors carrying hits_addend.
+func (uc *RateLimiterUseCase) CheckKeyHits(ctx context.Context, key string, max int64, window time.Duration, hits int64) (bool, error) {
+	if hits < 1 {
+		hits = 1
 	}
+	return uc.checkKey(ctx, key, max, window, hits, true)
+}
 
+func (uc *RateLimiterUseCase) checkKey(ctx context.Context, key string, max int64, window time.Duration, hits int64, block bool) (bool, error) {
 	blocked, err := uc.Repo.IsBlocked(ctx, key)
 	if err != nil {
 		return false, err
@@ -42,24 +124,60 @@ func (uc *RateLimiterUseCase) CheckAndIncrement(ctx context.Context, ip, token s
 		return false, nil
 	}
 
-	count, err := uc.Repo.Increment(ctx, key, uc.Window)
+	count, err := uc.incrementBy(ctx, key, hits, window)
 	if err != nil {
 		return false, err
 	}
+	if count > max && !block {
+		return false, nil
+	}
 	if count > max {
 		if err := uc.Repo.Block(ctx, key, uc.BlockDuration); err != nil {
 			return false, err
 		}
+		uc.publishBlocked(ctx, key)
 		return false, nil
 	}
 
 	return true, nil
 }
 
-func (uc *RateLimiterUseCase) GetLimitState(ctx context.Context, ip, token string) (*entity.RateLimit, error) {
-	key := ip
-	if token != "" {
-		key = "token:" + token
+// incrementBy counts n hits in one call when the repository supports it.
+func (uc *RateLimiterUseCase) incrementBy(ctx context.Context, key string, n int64, window time.Duration) (int64, error) {
+	if b, ok := uc.Repo.(repository.BatchIncrementer); ok && n > 1 {
+		return b.IncrementBy(ctx, key, n, window)
+	}
+	var count int64
+	for i := int64(0); i < n; i++ {
+		c, err := uc.Repo.Increment(ctx, key, window)
+		if err != nil {
+			return 0, err
+		}
+		count = c
 	}
+	return count, nil
+}
+
+func (uc *RateLimiterUseCase) GetLimitState(ctx context.Context, ip, token string) (*entity.RateLimit, error) {
+	return uc.GetLimitStateForTenant(ctx, "", ip, token)
+}
+
+func (uc *RateLimiterUseCase) GetLimitStateForTenant(ctx context.Context, tenant, ip, token string) (*entity.RateLimit, error) {
+	key, _ := uc.clientKey(tenant, ip, token)
 	return uc.Repo.GetState(ctx, key)
 }
+
+// BlockedFor returns how long key stays blocked, or zero when it is not.
+func (uc *RateLimiterUseCase) BlockedFor(ctx context.Context, key string) (time.Duration, error) {
+	state, err := uc.Repo.GetState(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	if state.BlockedUntil.IsZero() {
+		return 0, nil
+	}
+	if d := state.BlockedUntil.Sub(uc.Clock.Now()); d > 0 {
+		return d, nil
+	}
+	return 0, nil
+}
@@ -7,9 +7,21 @@ import (
 	"time"
 
 	"github.com/jpfigueredo/rate-limiter-challenge/internal/entity"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/repository"
 	"github.com/jpfigueredo/rate-limiter-challenge/internal/usecase"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/clock"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/storage"
 )
 
+// newUseCase runs the use case on a fake clock, so no test here depends on
+// wall-clock time. mockRepo stays for error injection, which a real backend
+// cannot fake.
+func newUseCase(repo repository.RateLimiterRepository) *usecase.RateLimiterUseCase {
+	uc := usecase.NewRateLimiterUseCase(repo, 5, 10, time.Second, 5*time.Minute)
+	uc.Clock = clock.NewFake(time.Unix(1000, 0))
+	return uc
+}
+
 type mockRepo struct {
 	count         int64
 	blocked       bool
@@ -34,7 +46,7 @@ func (m *mockRepo) GetState(ctx context.Context, key string) (*entity.RateLimit,
 }
 
 func TestCheckAndIncrementIPUnderLimit(t *testing.T) {
-	uc := usecase.NewRateLimiterUseCase(&mockRepo{count: 4}, 5, 10, time.Second, 5*time.Minute)
+	uc := newUseCase(&mockRepo{count: 4})
 	allowed, err := uc.CheckAndIncrement(context.Background(), "127.0.0.1", "")
 	if err != nil || !allowed {
 		t.Error("Expected allowed for IP under limit")
@@ -42,7 +54,7 @@ func TestCheckAndIncrementIPUnderLimit(t *testing.T) {
 }
 
 func TestCheckAndIncrementIPExceed(t *testing.T) {
-	uc := usecase.NewRateLimiterUseCase(&mockRepo{count: 6}, 5, 10, time.Second, 5*time.Minute)
+	uc := newUseCase(&mockRepo{count: 6})
 	allowed, err := uc.CheckAndIncrement(context.Background(), "127.0.0.1", "")
 	if err != nil || allowed {
 		t.Error("Expected denied for IP exceed")
@@ -50,7 +62,7 @@ func TestCheckAndIncrementIPExceed(t *testing.T) {
 }
 
 func TestCheckAndIncrementTokenPriority(t *testing.T) {
-	uc := usecase.NewRateLimiterUseCase(&mockRepo{count: 6}, 5, 10, time.Second, 5*time.Minute)
+	uc := newUseCase(&mockRepo{count: 6})
 	allowed, err := uc.CheckAndIncrement(context.Background(), "127.0.0.1", "mytoken")
 	if err != nil || !allowed {
 		t.Error("Expected allowed for token over IP limit")
@@ -58,7 +70,7 @@ func TestCheckAndIncrementTokenPriority(t *testing.T) {
 }
 
 func TestCheckAndIncrementTokenExceed(t *testing.T) {
-	uc := usecase.NewRateLimiterUseCase(&mockRepo{count: 11}, 5, 10, time.Second, 5*time.Minute)
+	uc := newUseCase(&mockRepo{count: 11})
 	allowed, err := uc.CheckAndIncrement(context.Background(), "127.0.0.1", "mytoken")
 	if err != nil || allowed {
 		t.Error("Expected denied for token exceed")
@@ -66,7 +78,7 @@ func TestCheckAndIncrementTokenExceed(t *testing.T) {
 }
 
 func TestCheckAndIncrementBlocked(t *testing.T) {
-	uc := usecase.NewRateLimiterUseCase(&mockRepo{blocked: true}, 5, 10, time.Second, 5*time.Minute)
+	uc := newUseCase(&mockRepo{blocked: true})
 	allowed, err := uc.CheckAndIncrement(context.Background(), "127.0.0.1", "")
 	if err != nil || allowed {
 		t.Error("Expected denied when already blocked")
@@ -74,7 +86,7 @@ func TestCheckAndIncrementBlocked(t *testing.T) {
 }
 
 func TestCheckAndIncrementBlockError(t *testing.T) {
-	uc := usecase.NewRateLimiterUseCase(&mockRepo{count: 6, blockErr: errors.New("block fail")}, 5, 10, time.Second, 5*time.Minute)
+	uc := newUseCase(&mockRepo{count: 6, blockErr: errors.New("block fail")})
 	_, err := uc.CheckAndIncrement(context.Background(), "127.0.0.1", "")
 	if err == nil {
 		t.Error("Expected error when block fails")
@@ -82,7 +94,7 @@ func TestCheckAndIncrementBlockError(t *testing.T) {
 }
 
 func TestCheckAndIncrementIncError(t *testing.T) {
-	uc := usecase.NewRateLimiterUseCase(&mockRepo{incErr: errors.New("inc fail")}, 5, 10, time.Second, 5*time.Minute)
+	uc := newUseCase(&mockRepo{incErr: errors.New("inc fail")})
 	_, err := uc.CheckAndIncrement(context.Background(), "127.0.0.1", "")
 	if err == nil {
 		t.Error("Expected error when increment fails")
@@ -90,7 +102,7 @@ func TestCheckAndIncrementIncError(t *testing.T) {
 }
 
 func TestGetLimitState(t *testing.T) {
-	uc := usecase.NewRateLimiterUseCase(&mockRepo{count: 3}, 5, 10, time.Second, 5*time.Minute)
+	uc := newUseCase(&mockRepo{count: 3})
 	state, err := uc.GetLimitState(context.Background(), "127.0.0.1", "")
 	if err != nil || state.Key != "127.0.0.1" || state.Count != 3 {
 		t.Errorf("Expected correct state for IP: got Key=%s, Count=%d, err=%v", state.Key, state.Count, err)
@@ -102,7 +114,7 @@ func TestGetLimitState(t *testing.T) {
 	}
 }
 func TestGetLimitStateError(t *testing.T) {
-	uc := usecase.NewRateLimiterUseCase(&mockRepo{stateErr: errors.New("state fail")}, 5, 10, time.Second, 5*time.Minute)
+	uc := newUseCase(&mockRepo{stateErr: errors.New("state fail")})
 	_, err := uc.GetLimitState(context.Background(), "127.0.0.1", "")
 	if err == nil {
 		t.Error("Expected error when get state fails")
@@ -110,7 +122,7 @@ func TestGetLimitStateError(t *testing.T) {
 }
 
 func TestCheckAndIncrementAtLimit(t *testing.T) {
-	uc := usecase.NewRateLimiterUseCase(&mockRepo{count: 5}, 5, 10, time.Second, 5*time.Minute)
+	uc := newUseCase(&mockRepo{count: 5})
 	allowed, err := uc.CheckAndIncrement(context.Background(), "127.0.0.1", "")
 	if err != nil || !allowed {
 		t.Error("Expected allowed at exact limit")
@@ -118,7 +130,7 @@ func TestCheckAndIncrementAtLimit(t *testing.T) {
 }
 
 func TestCheckAndIncrementIsBlockedError(t *testing.T) {
-	uc := usecase.NewRateLimiterUseCase(&mockRepo{blockCheckErr: errors.New("check fail")}, 5, 10, time.Second, 5*time.Minute)
+	uc := newUseCase(&mockRepo{blockCheckErr: errors.New("check fail")})
 	_, err := uc.CheckAndIncrement(context.Background(), "127.0.0.1", "")
 	if err == nil {
 		t.Error("Expected error when isblocked fails")
@@ -127,7 +139,7 @@ func TestCheckAndIncrementIsBlockedError(t *testing.T) {
 
 func TestGetLimitStateToken(t *testing.T) {
 	expected := &entity.RateLimit{Key: "token:mytoken", Count: 5}
-	uc := usecase.NewRateLimiterUseCase(&mockRepo{state: expected}, 5, 10, time.Second, 5*time.Minute)
+	uc := newUseCase(&mockRepo{state: expected})
 	state, err := uc.GetLimitState(context.Background(), "127.0.0.1", "mytoken")
 	if err != nil || state.Key != "token:mytoken" {
 		t.Error("Expected token key in state")
@@ -135,7 +147,7 @@ func TestGetLimitStateToken(t *testing.T) {
 }
 
 func TestCheckAndIncrementZeroCount(t *testing.T) {
-	uc := usecase.NewRateLimiterUseCase(&mockRepo{count: 0}, 5, 10, time.Second, 5*time.Minute)
+	uc := newUseCase(&mockRepo{count: 0})
 	allowed, err := uc.CheckAndIncrement(context.Background(), "127.0.0.1", "")
 	if err != nil || !allowed {
 		t.Error("Expected allowed with zero count")
@@ -143,7 +155,7 @@ func TestCheckAndIncrementZeroCount(t *testing.T) {
 }
 
 func TestCheckAndIncrementBlockedWithToken(t *testing.T) {
-	uc := usecase.NewRateLimiterUseCase(&mockRepo{blocked: true}, 5, 10, time.Second, 5*time.Minute)
+	uc := newUseCase(&mockRepo{blocked: true})
 	allowed, err := uc.CheckAndIncrement(context.Background(), "127.0.0.1", "mytoken")
 	if err != nil || allowed {
 		t.Error("Expected denied when blocked with token")
@@ -151,9 +163,152 @@ func TestCheckAndIncrementBlockedWithToken(t *testing.T) {
 }
 
 func TestGetLimitStateZero(t *testing.T) {
-	uc := usecase.NewRateLimiterUseCase(&mockRepo{state: &entity.RateLimit{Key: "127.0.0.1", Count: 0}}, 5, 10, time.Second, 5*time.Minute)
+	uc := newUseCase(&mockRepo{state: &entity.RateLimit{Key: "127.0.0.1", Count: 0}})
 	state, err := uc.GetLimitState(context.Background(), "127.0.0.1", "")
 	if err != nil || state.Count != 0 {
 		t.Error("Expected zero count state")
 	}
 }
+
+func TestCheckAndIncrementWindowAndBlockExpire(t *testing.T) {
+	clk := clock.NewFake(time.Unix(1000, 0))
+	uc := usecase.NewRateLimiterUseCase(storage.NewMemoryRateLimiter(clk), 2, 10, time.Second, 5*time.Second)
+	uc.Clock = clk
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if allowed, err := uc.CheckAndIncrement(ctx, "127.0.0.1", ""); err != nil || !allowed {
+			t.Fatalf("Expected request %d allowed, got %v %v", i+1, allowed, err)
+		}
+	}
+	clk.Advance(time.Second)
+	if allowed, _ := uc.CheckAndIncrement(ctx, "127.0.0.1", ""); !allowed {
+		t.Fatal("Expected the window to reset after it elapsed")
+	}
+
+	_, _ = uc.CheckAndIncrement(ctx, "127.0.0.1", "")
+	if allowed, _ := uc.CheckAndIncrement(ctx, "127.0.0.1", ""); allowed {
+		t.Fatal("Expected denied over the limit")
+	}
+	if d, err := uc.BlockedFor(ctx, "127.0.0.1"); err != nil || d != 5*time.Second {
+		t.Errorf("Expected blocked for 5s, got %v %v", d, err)
+	}
+
+	clk.Advance(4 * time.Second)
+	if allowed, _ := uc.CheckAndIncrement(ctx, "127.0.0.1", ""); allowed {
+		t.Error("Expected still blocked before BlockDuration elapsed")
+	}
+	if d, _ := uc.BlockedFor(ctx, "127.0.0.1"); d != time.Second {
+		t.Errorf("Expected 1s left on the block, got %v", d)
+	}
+
+	clk.Advance(time.Second)
+	if allowed, _ := uc.CheckAndIncrement(ctx, "127.0.0.1", ""); !allowed {
+		t.Error("Expected allowed once the block expired")
+	}
+	if d, _ := uc.BlockedFor(ctx, "127.0.0.1"); d != 0 {
+		t.Errorf("Expected no block left, got %v", d)
+	}
+}
+
+type recordingRepo struct {
+	mockRepo
+	key    string
+	window time.Duration
+}
+
+func (r *recordingRepo) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
+	r.key = key
+	r.window = window
+	return r.count, r.incErr
+}
+
+func TestCheckKeyUsesGivenLimitAndWindow(t *testing.T) {
+	repo := &recordingRepo{mockRepo: mockRepo{count: 3}}
+	uc := newUseCase(repo)
+
+	allowed, err := uc.CheckKey(context.Background(), "custom", 3, time.Minute)
+	if err != nil || !allowed || repo.key != "custom" || repo.window != time.Minute {
+		t.Errorf("Expected allowed with custom key and window: got allowed=%v key=%s window=%v err=%v", allowed, repo.key, repo.window, err)
+	}
+
+	allowed, err = uc.CheckKey(context.Background(), "custom", 2, time.Minute)
+	if err != nil || allowed {
+		t.Error("Expected denied when count exceeds given limit")
+	}
+}
+
+func TestCheckAndIncrementForTenantNamespacesKeys(t *testing.T) {
+	repo := &recordingRepo{mockRepo: mockRepo{count: 1}}
+	uc := newUseCase(repo)
+	uc.Tenants = map[string]entity.TenantLimits{"acme": {}}
+
+	_, _ = uc.CheckAndIncrementForTenant(context.Background(), "acme", "127.0.0.1", "")
+	if repo.key != "tenant:acme:127.0.0.1" {
+		t.Errorf("Expected tenant namespaced IP key, got %s", repo.key)
+	}
+	_, _ = uc.CheckAndIncrementForTenant(context.Background(), "acme", "127.0.0.1", "mytoken")
+	if repo.key != "tenant:acme:token:mytoken" {
+		t.Errorf("Expected tenant namespaced token key, got %s", repo.key)
+	}
+	_, _ = uc.CheckAndIncrement(context.Background(), "127.0.0.1", "")
+	if repo.key != "127.0.0.1" {
+		t.Errorf("Expected plain key without tenant, got %s", repo.key)
+	}
+}
+
+func TestCheckAndIncrementForTenantLimits(t *testing.T) {
+	uc := newUseCase(&mockRepo{count: 4})
+	uc.Tenants = map[string]entity.TenantLimits{"small": {MaxRequests: 3, MaxTokenRequests: 3}}
+
+	allowed, err := uc.CheckAndIncrementForTenant(context.Background(), "small", "127.0.0.1", "")
+	if err != nil || allowed {
+		t.Error("Expected denied by tenant IP limit")
+	}
+	allowed, err = uc.CheckAndIncrementForTenant(context.Background(), "small", "127.0.0.1", "mytoken")
+	if err != nil || allowed {
+		t.Error("Expected denied by tenant token limit")
+	}
+}
+
+func TestCheckAndIncrementForTenantRotationSharesIPLimit(t *testing.T) {
+	repo := &recordingRepo{}
+	uc := newUseCase(repo)
+	uc.Tenants = map[string]entity.TenantLimits{"acme": {}}
+
+	tests := []struct{ tenant, key string }{
+		{"a", "127.0.0.1"},
+		{"b", "127.0.0.1"},
+		{"acme", "tenant:acme:127.0.0.1"},
+	}
+	for _, tc := range tests {
+		if _, err := uc.CheckAndIncrementForTenant(context.Background(), tc.tenant, "127.0.0.1", ""); err != nil || repo.key != tc.key {
+			t.Errorf("Expected tenant %q to count on %q, got %q %v", tc.tenant, tc.key, repo.key, err)
+		}
+	}
+
+	repo.count = 6
+	allowed, _ := uc.CheckAndIncrementForTenant(context.Background(), "rotated", "127.0.0.1", "")
+	if allowed {
+		t.Error("Expected rotating tenants to stay under the per-IP limit")
+	}
+}
+
+func TestCheckAndIncrementForTenantAggregateCap(t *testing.T) {
+	uc := newUseCase(&mockRepo{count: 4})
+	uc.Tenants = map[string]entity.TenantLimits{"acme": {MaxTotal: 3}}
+
+	allowed, err := uc.CheckAndIncrementForTenant(context.Background(), "acme", "127.0.0.1", "")
+	if err != nil || allowed {
+		t.Error("Expected denied by tenant aggregate cap")
+	}
+}
+
+func TestGetLimitStateForTenant(t *testing.T) {
+	uc := newUseCase(&mockRepo{count: 2})
+	uc.Tenants = map[string]entity.TenantLimits{"acme": {}}
+	state, err := uc.GetLimitStateForTenant(context.Background(), "acme", "127.0.0.1", "mytoken")
+	if err != nil || state.Key != "tenant:acme:token:mytoken" {
+		t.Errorf("Expected tenant token key in state, got %+v", state)
+	}
+}
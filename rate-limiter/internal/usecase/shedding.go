@@ -0,0 +1,56 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/entity"
+)
+
+// LoadShedder splits a global capacity budget per window between priority
+// classes. A class is admitted while the requests admitted so far in the
+// window stay under its share of Capacity, so as the budget fills up low
+// priority traffic is rejected first; classes without a share are never shed
+// but still consume the budget. Rejected requests do not count, otherwise a
+// flood of low priority traffic would starve the higher classes.
+type LoadShedder struct {
+	Capacity int64
+	Shares   map[entity.Priority]float64
+}
+
+func NewLoadShedder(capacity int64) *LoadShedder {
+	return &LoadShedder{
+		Capacity: capacity,
+		Shares: map[entity.Priority]float64{
+			entity.PriorityLow:    0.5,
+			entity.PriorityNormal: 0.8,
+			entity.PriorityHigh:   1.0,
+		},
+	}
+}
+
+const shedKey = "shed:budget"
+
+// Admit reports whether a request of the given priority fits in the current
+// capacity budget. It always admits when no shedder is configured. The check
+// and the increment are not atomic, so concurrent instances may overshoot a
+// share by a few requests.
+func (uc *RateLimiterUseCase) Admit(ctx context.Context, priority entity.Priority) (bool, error) {
+	if uc.Shedder == nil {
+		return true, nil
+	}
+
+	if share, ok := uc.Shedder.Shares[priority]; ok {
+		state, err := uc.Repo.GetState(ctx, shedKey)
+		if err != nil {
+			return false, err
+		}
+		if float64(state.Count+1) > share*float64(uc.Shedder.Capacity) {
+			return false, nil
+		}
+	}
+
+	if _, err := uc.Repo.Increment(ctx, shedKey, uc.Window); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,70 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/entity"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/usecase"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/clock"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/storage"
+)
+
+func newShedding(capacity int64) (*usecase.RateLimiterUseCase, *clock.Fake) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	uc := usecase.NewRateLimiterUseCase(storage.NewMemoryRateLimiter(clk), 100, 100, time.Second, time.Minute)
+	uc.Clock = clk
+	uc.Shedder = usecase.NewLoadShedder(capacity)
+	return uc, clk
+}
+
+func admitted(uc *usecase.RateLimiterUseCase, priority entity.Priority, n int) int {
+	var count int
+	for i := 0; i < n; i++ {
+		if ok, err := uc.Admit(context.Background(), priority); err == nil && ok {
+			count++
+		}
+	}
+	return count
+}
+
+func TestAdmitShedsLowerClassesFirst(t *testing.T) {
+	uc, _ := newShedding(10)
+
+	if got := admitted(uc, entity.PriorityLow, 20); got != 5 {
+		t.Errorf("Expected low priority capped at half the budget, got %d", got)
+	}
+	if got := admitted(uc, entity.PriorityNormal, 20); got != 3 {
+		t.Errorf("Expected normal priority to fill up to 80%%, got %d", got)
+	}
+	if got := admitted(uc, entity.PriorityHigh, 20); got != 2 {
+		t.Errorf("Expected high priority to use the remaining budget, got %d", got)
+	}
+	if got := admitted(uc, entity.PriorityCritical, 5); got != 5 {
+		t.Errorf("Expected critical priority never shed, got %d", got)
+	}
+	if got := admitted(uc, entity.PriorityLow, 1); got != 0 {
+		t.Errorf("Expected low priority still shed, got %d", got)
+	}
+}
+
+func TestAdmitBudgetResetsEachWindow(t *testing.T) {
+	uc, clk := newShedding(4)
+	admitted(uc, entity.PriorityHigh, 4)
+	if got := admitted(uc, entity.PriorityHigh, 1); got != 0 {
+		t.Fatalf("Expected budget exhausted, got %d", got)
+	}
+
+	clk.Advance(time.Second)
+	if got := admitted(uc, entity.PriorityLow, 4); got != 2 {
+		t.Errorf("Expected fresh budget in the next window, got %d", got)
+	}
+}
+
+func TestAdmitWithoutShedder(t *testing.T) {
+	uc := usecase.NewRateLimiterUseCase(&mockRepo{}, 5, 10, time.Second, time.Minute)
+	if ok, err := uc.Admit(context.Background(), entity.PriorityLow); err != nil || !ok {
+		t.Error("Expected every request admitted without shedder")
+	}
+}
@@ -0,0 +1,115 @@
+package usecase
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// WaitQueue holds requests from the listed tokens that exceed their limit
+// instead of rejecting them right away. A held request retries every Poll
+// until a slot frees up, MaxWait elapses or its context is done. At most
+// MaxDepth requests wait at the same time; beyond that they are rejected as
+// usual. Keys checked in wait mode are never blocked, since the point is to
+// smooth the traffic of trusted clients rather than punish it.
+type WaitQueue struct {
+	MaxWait  time.Duration
+	MaxDepth int64
+	Poll     time.Duration
+	Tokens   map[string]bool
+
+	depth atomic.Int64
+}
+
+func NewWaitQueue(maxWait time.Duration, maxDepth int64, tokens ...string) *WaitQueue {
+	q := &WaitQueue{
+		MaxWait:  maxWait,
+		MaxDepth: maxDepth,
+		Poll:     50 * time.Millisecond,
+		Tokens:   make(map[string]bool, len(tokens)),
+	}
+	for _, token := range tokens {
+		q.Tokens[token] = true
+	}
+	return q
+}
+
+// Depth returns how many requests are currently waiting.
+func (q *WaitQueue) Depth() int64 {
+	return q.depth.Load()
+}
+
+func (q *WaitQueue) applies(token string) bool {
+	return q != nil && token != "" && q.Tokens[token]
+}
+
+// CheckAndWaitForTenant behaves like CheckAndIncrementForTenant, except that
+// clients in the wait queue are held until they fit in the limit. It returns
+// the context error when the caller gives up while waiting.
+func (uc *RateLimiterUseCase) CheckAndWaitForTenant(ctx context.Context, tenant, ip, token string) (bool, error) {
+	q := uc.Queue
+	if !q.applies(token) {
+		return uc.CheckAndIncrementForTenant(ctx, tenant, ip, token)
+	}
+
+	if allowed, err := uc.tryTenant(ctx, tenant, ip, token); err != nil || allowed {
+		return allowed, err
+	}
+
+	if q.depth.Add(1) > q.MaxDepth {
+		q.depth.Add(-1)
+		return false, nil
+	}
+	defer q.depth.Add(-1)
+
+	deadline := uc.Clock.Now().Add(q.MaxWait)
+	for {
+		wait := q.Poll
+		if remaining := deadline.Sub(uc.Clock.Now()); remaining < wait {
+			wait = remaining
+		}
+		if wait <= 0 {
+			return false, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-uc.Clock.After(wait):
+		}
+
+		if allowed, err := uc.tryTenant(ctx, tenant, ip, token); err != nil || allowed {
+			return allowed, err
+		}
+	}
+}
+
+// tryTenant counts the request only when a peek at every limit (client,
+// tenant total and adaptive) shows room for it, so waiting clients do not
+// keep inflating the counters while they are over a limit.
+func (uc *RateLimiterUseCase) tryTenant(ctx context.Context, tenant, ip, token string) (bool, error) {
+	key, max := uc.clientKey(tenant, ip, token)
+	fits, err := uc.fits(ctx, key, max)
+	if err != nil || !fits {
+		return false, err
+	}
+	if limits, ok := uc.Tenants[tenant]; ok && tenant != "" && limits.MaxTotal > 0 {
+		if fits, err = uc.fits(ctx, tenantPrefix(tenant)+"total", limits.MaxTotal); err != nil || !fits {
+			return false, err
+		}
+	}
+	if uc.Adaptive != nil {
+		if fits, err = uc.fits(ctx, globalKey, uc.Adaptive.Limit()); err != nil || !fits {
+			return false, err
+		}
+	}
+	return uc.checkTenant(ctx, tenant, ip, token, false)
+}
+
+func (uc *RateLimiterUseCase) fits(ctx context.Context, key string, max int64) (bool, error) {
+	state, err := uc.Repo.GetState(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	return state.Count < max && state.BlockedUntil.IsZero(), nil
+}
@@ -0,0 +1,237 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/entity"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/usecase"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/clock"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/storage"
+)
+
+type waitResult struct {
+	allowed bool
+	err     error
+}
+
+func newWaiting(maxWait time.Duration, depth int64) (*usecase.RateLimiterUseCase, *clock.Fake) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	uc := usecase.NewRateLimiterUseCase(storage.NewMemoryRateLimiter(clk), 1, 2, time.Second, time.Minute)
+	uc.Clock = clk
+	uc.Queue = usecase.NewWaitQueue(maxWait, depth, "internal")
+	uc.Queue.Poll = 100 * time.Millisecond
+	return uc, clk
+}
+
+func waitAsync(ctx context.Context, uc *usecase.RateLimiterUseCase, token string) <-chan waitResult {
+	done := make(chan waitResult, 1)
+	go func() {
+		allowed, err := uc.CheckAndWaitForTenant(ctx, "", "1.1.1.1", token)
+		done <- waitResult{allowed, err}
+	}()
+	return done
+}
+
+// drive advances the fake clock one poll at a time until the waiting request
+// returns.
+func drive(t *testing.T, clk *clock.Fake, done <-chan waitResult) waitResult {
+	t.Helper()
+	for i := 0; i < 1000; i++ {
+		select {
+		case res := <-done:
+			return res
+		default:
+		}
+		if clk.Waiters() > 0 {
+			clk.Advance(100 * time.Millisecond)
+		} else {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	t.Fatal("Expected waiting request to return")
+	return waitResult{}
+}
+
+func parked(t *testing.T, clk *clock.Fake) {
+	t.Helper()
+	for i := 0; clk.Waiters() == 0; i++ {
+		if i > 1000 {
+			t.Fatal("Expected request to be waiting")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWaitHoldsUntilSlotFrees(t *testing.T) {
+	uc, clk := newWaiting(2*time.Second, 10)
+	ctx := context.Background()
+	uc.CheckAndWaitForTenant(ctx, "", "1.1.1.1", "internal")
+	uc.CheckAndWaitForTenant(ctx, "", "1.1.1.1", "internal")
+
+	start := clk.Now()
+	res := drive(t, clk, waitAsync(ctx, uc, "internal"))
+	if !res.allowed || res.err != nil {
+		t.Fatalf("Expected held request admitted, got %v %v", res.allowed, res.err)
+	}
+	if waited := clk.Now().Sub(start); waited != time.Second {
+		t.Errorf("Expected request held until the window reset, got %v", waited)
+	}
+	if blocked, _ := uc.Repo.IsBlocked(ctx, "token:internal"); blocked {
+		t.Error("Expected key not blocked in wait mode")
+	}
+	if uc.Queue.Depth() != 0 {
+		t.Errorf("Expected empty queue, got %d", uc.Queue.Depth())
+	}
+}
+
+func TestWaitGivesUpAfterMaxWait(t *testing.T) {
+	uc, clk := newWaiting(500*time.Millisecond, 10)
+	ctx := context.Background()
+	uc.CheckAndWaitForTenant(ctx, "", "1.1.1.1", "internal")
+	uc.CheckAndWaitForTenant(ctx, "", "1.1.1.1", "internal")
+
+	res := drive(t, clk, waitAsync(ctx, uc, "internal"))
+	if res.allowed || res.err != nil {
+		t.Errorf("Expected request rejected after max wait, got %v %v", res.allowed, res.err)
+	}
+	if blocked, _ := uc.Repo.IsBlocked(ctx, "token:internal"); blocked {
+		t.Error("Expected key not blocked in wait mode")
+	}
+}
+
+func TestWaitQueueDepth(t *testing.T) {
+	uc, clk := newWaiting(2*time.Second, 1)
+	ctx := context.Background()
+	uc.CheckAndWaitForTenant(ctx, "", "1.1.1.1", "internal")
+	uc.CheckAndWaitForTenant(ctx, "", "1.1.1.1", "internal")
+
+	first := waitAsync(ctx, uc, "internal")
+	parked(t, clk)
+	if allowed, err := uc.CheckAndWaitForTenant(ctx, "", "1.1.1.1", "internal"); allowed || err != nil {
+		t.Errorf("Expected request rejected when queue is full, got %v %v", allowed, err)
+	}
+	if res := drive(t, clk, first); !res.allowed {
+		t.Error("Expected queued request admitted")
+	}
+}
+
+func TestWaitHonoursCancellation(t *testing.T) {
+	uc, clk := newWaiting(2*time.Second, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	uc.CheckAndWaitForTenant(ctx, "", "1.1.1.1", "internal")
+	uc.CheckAndWaitForTenant(ctx, "", "1.1.1.1", "internal")
+
+	done := waitAsync(ctx, uc, "internal")
+	parked(t, clk)
+	cancel()
+	res := <-done
+	if res.allowed || !errors.Is(res.err, context.Canceled) {
+		t.Errorf("Expected cancelled request, got %v %v", res.allowed, res.err)
+	}
+	if uc.Queue.Depth() != 0 {
+		t.Errorf("Expected empty queue, got %d", uc.Queue.Depth())
+	}
+}
+
+func TestWaitOnlyForListedTokens(t *testing.T) {
+	uc, clk := newWaiting(2*time.Second, 10)
+	ctx := context.Background()
+	uc.CheckAndWaitForTenant(ctx, "", "1.1.1.1", "")
+
+	if allowed, _ := uc.CheckAndWaitForTenant(ctx, "", "1.1.1.1", ""); allowed {
+		t.Error("Expected anonymous request rejected immediately")
+	}
+	if clk.Waiters() != 0 {
+		t.Error("Expected no waiting request")
+	}
+	if blocked, _ := uc.Repo.IsBlocked(ctx, "1.1.1.1"); !blocked {
+		t.Error("Expected anonymous client blocked as usual")
+	}
+}
+
+// polls lets the parked request wake up n times without reaching the window
+// reset.
+func polls(t *testing.T, clk *clock.Fake, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		parked(t, clk)
+		clk.Advance(100 * time.Millisecond)
+	}
+	parked(t, clk)
+}
+
+func count(t *testing.T, uc *usecase.RateLimiterUseCase, key string) int64 {
+	t.Helper()
+	state, err := uc.Repo.GetState(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state.Count
+}
+
+func TestWaitDoesNotCountRejectedAttempts(t *testing.T) {
+	uc, clk := newWaiting(2*time.Second, 10)
+	ctx := context.Background()
+	uc.CheckAndWaitForTenant(ctx, "", "1.1.1.1", "internal")
+	uc.CheckAndWaitForTenant(ctx, "", "1.1.1.1", "internal")
+
+	done := waitAsync(ctx, uc, "internal")
+	polls(t, clk, 3)
+	if c := count(t, uc, "token:internal"); c != 2 {
+		t.Errorf("Expected waiting request not counted, got %d", c)
+	}
+	if res := drive(t, clk, done); !res.allowed {
+		t.Error("Expected held request admitted")
+	}
+	if c := count(t, uc, "token:internal"); c != 1 {
+		t.Errorf("Expected only the admitted request counted in the new window, got %d", c)
+	}
+}
+
+func TestWaitPeeksSharedLimits(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(uc *usecase.RateLimiterUseCase)
+		key   string
+	}{
+		{"tenant total", func(uc *usecase.RateLimiterUseCase) {
+			uc.Tenants = map[string]entity.TenantLimits{"acme": {MaxTotal: 1}}
+		}, "tenant:acme:total"},
+		{"adaptive", func(uc *usecase.RateLimiterUseCase) {
+			uc.Adaptive = usecase.NewAdaptiveLimiter(1, 1, time.Second, 1)
+		}, "global"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			uc, clk := newWaiting(2*time.Second, 10)
+			tc.setup(uc)
+			ctx := context.Background()
+			if allowed, _ := uc.CheckAndIncrementForTenant(ctx, "acme", "2.2.2.2", ""); !allowed {
+				t.Fatal("Expected first client admitted")
+			}
+
+			done := make(chan waitResult, 1)
+			go func() {
+				allowed, err := uc.CheckAndWaitForTenant(ctx, "acme", "1.1.1.1", "internal")
+				done <- waitResult{allowed, err}
+			}()
+			polls(t, clk, 3)
+			client := "token:internal"
+			if uc.Tenants != nil {
+				client = "tenant:acme:token:internal"
+			}
+			if c := count(t, uc, client); c != 0 {
+				t.Errorf("Expected client counter untouched while waiting, got %d", c)
+			}
+			if c := count(t, uc, tc.key); c != 1 {
+				t.Errorf("Expected shared counter untouched while waiting, got %d", c)
+			}
+			if res := drive(t, clk, done); !res.allowed {
+				t.Error("Expected held request admitted after the window reset")
+			}
+		})
+	}
+}
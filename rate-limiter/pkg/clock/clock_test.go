@@ -0,0 +1,60 @@
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/clock"
+)
+
+func TestFakeAdvance(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+
+	clk.Advance(1500 * time.Millisecond)
+	if !clk.Now().Equal(start.Add(1500 * time.Millisecond)) {
+		t.Errorf("Expected time to advance, got %v", clk.Now())
+	}
+
+	clk.Set(start)
+	if !clk.Now().Equal(start) {
+		t.Errorf("Expected time to be set, got %v", clk.Now())
+	}
+}
+
+func TestFakeAfter(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	ch := clk.After(time.Second)
+
+	clk.Advance(999 * time.Millisecond)
+	select {
+	case <-ch:
+		t.Fatal("Expected After not to fire before deadline")
+	default:
+	}
+	if clk.Waiters() != 1 {
+		t.Errorf("Expected one pending waiter, got %d", clk.Waiters())
+	}
+
+	clk.Advance(time.Millisecond)
+	select {
+	case at := <-ch:
+		if !at.Equal(time.Unix(1, 0)) {
+			t.Errorf("Expected fire time at deadline, got %v", at)
+		}
+	default:
+		t.Fatal("Expected After to fire at deadline")
+	}
+	if clk.Waiters() != 0 {
+		t.Errorf("Expected no pending waiters, got %d", clk.Waiters())
+	}
+}
+
+func TestFakeAfterNonPositive(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	select {
+	case <-clk.After(0):
+	default:
+		t.Fatal("Expected After(0) to fire immediately")
+	}
+}
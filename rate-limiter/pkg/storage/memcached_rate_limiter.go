@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/entity"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/repository"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/clock"
+)
+
+// MemcachedRateLimiter counts with atomic incr/add. Memcached expirations have
+// one second granularity, so windows and blocks are rounded up to whole
+// seconds; the block value stores the exact deadline for IsBlocked/GetState.
+type MemcachedRateLimiter struct {
+	Client *memcache.Client
+	Clock  clock.Clock
+}
+
+var (
+	_ repository.RateLimiterRepository = (*MemcachedRateLimiter)(nil)
+	_ repository.BatchIncrementer      = (*MemcachedRateLimiter)(nil)
+)
+
+func NewMemcachedRateLimiter(addrs ...string) *MemcachedRateLimiter {
+	return &MemcachedRateLimiter{Client: memcache.New(addrs...), Clock: clock.Real{}}
+}
+
+func (m *MemcachedRateLimiter) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
+	return m.IncrementBy(ctx, key, 1, window)
+}
+
+func (m *MemcachedRateLimiter) IncrementBy(ctx context.Context, key string, n int64, window time.Duration) (int64, error) {
+	rateKey := memcachedKey("rate:", key)
+	val, err := m.Client.Increment(rateKey, uint64(n))
+	if err == nil {
+		return int64(val), nil
+	}
+	if !errors.Is(err, memcache.ErrCacheMiss) {
+		return 0, err
+	}
+
+	err = m.Client.Add(&memcache.Item{Key: rateKey, Value: []byte(strconv.FormatInt(n, 10)), Expiration: m.expiration(window)})
+	if err == nil {
+		return n, nil
+	}
+	if !errors.Is(err, memcache.ErrNotStored) {
+		return 0, err
+	}
+	// Another instance created the counter between our incr and add.
+	val, err = m.Client.Increment(rateKey, uint64(n))
+	if err != nil {
+		return 0, err
+	}
+	return int64(val), nil
+}
+
+func (m *MemcachedRateLimiter) Block(ctx context.Context, key string, blockDuration time.Duration) error {
+	if blockDuration <= 0 {
+		return nil
+	}
+	until := m.Clock.Now().Add(blockDuration)
+	return m.Client.Set(&memcache.Item{
+		Key:        memcachedKey("block:", key),
+		Value:      []byte(strconv.FormatInt(until.UnixNano(), 10)),
+		Expiration: m.expiration(blockDuration),
+	})
+}
+
+func (m *MemcachedRateLimiter) IsBlocked(ctx context.Context, key string) (bool, error) {
+	until, err := m.blockedUntil(key)
+	if err != nil {
+		return false, err
+	}
+	return !until.IsZero(), nil
+}
+
+func (m *MemcachedRateLimiter) GetState(ctx context.Context, key string) (*entity.RateLimit, error) {
+	var count int64
+	item, err := m.Client.Get(memcachedKey("rate:", key))
+	if err == nil {
+		count, err = strconv.ParseInt(strings.TrimSpace(string(item.Value)), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, memcache.ErrCacheMiss) {
+		return nil, err
+	}
+
+	blockedUntil, err := m.blockedUntil(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.RateLimit{
+		Key:          key,
+		Count:        count,
+		BlockedUntil: blockedUntil,
+	}, nil
+}
+
+func (m *MemcachedRateLimiter) blockedUntil(key string) (time.Time, error) {
+	item, err := m.Client.Get(memcachedKey("block:", key))
+	if errors.Is(err, memcache.ErrCacheMiss) {
+		return time.Time{}, nil
+	} else if err != nil {
+		return time.Time{}, err
+	}
+
+	nanos, err := strconv.ParseInt(string(item.Value), 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	until := time.Unix(0, nanos)
+	if !m.Clock.Now().Before(until) {
+		return time.Time{}, nil
+	}
+	return until, nil
+}
+
+// maxRelativeExpiration is the largest expiration memcached treats as
+// relative; anything longer is interpreted as a unix timestamp.
+const maxRelativeExpiration = 30 * 24 * time.Hour
+
+func (m *MemcachedRateLimiter) expiration(d time.Duration) int32 {
+	if d > maxRelativeExpiration {
+		return int32(m.Clock.Now().Add(d).Unix())
+	}
+	seconds := int32((d + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
+
+// memcachedKey hashes keys memcached would reject (longer than 250 bytes or
+// containing spaces and control characters), e.g. arbitrary API tokens.
+func memcachedKey(prefix, key string) string {
+	full := prefix + key
+	if len(full) <= 250 && !strings.ContainsFunc(full, func(r rune) bool { return r <= ' ' || r == 0x7f }) {
+		return full
+	}
+	sum := sha1.Sum([]byte(key))
+	return prefix + hex.EncodeToString(sum[:])
+}
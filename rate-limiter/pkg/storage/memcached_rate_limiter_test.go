@@ -0,0 +1,32 @@
+package storage_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/clock"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/storage"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/storage/storagetest"
+)
+
+// Set MEMCACHED_TEST_ADDR, e.g. localhost:11211, to run against a real
+// server. Expiration is server side, so the suite sleeps through windows.
+func TestMemcachedConformance(t *testing.T) {
+	addr := os.Getenv("MEMCACHED_TEST_ADDR")
+	if addr == "" {
+		t.Skip("MEMCACHED_TEST_ADDR not set")
+	}
+
+	storagetest.Run(t, func(t *testing.T) storagetest.Harness {
+		repo := storage.NewMemcachedRateLimiter(strings.Split(addr, ",")...)
+		return storagetest.Harness{
+			Repo: repo,
+			Now:  clock.Real{}.Now,
+			Advance: func(d time.Duration) {
+				time.Sleep(d + time.Second)
+			},
+		}
+	})
+}
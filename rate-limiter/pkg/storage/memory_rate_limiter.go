@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/entity"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/repository"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/clock"
+)
+
+type memoryCounter struct {
+	count     int64
+	expiresAt time.Time
+}
+
+// MemoryRateLimiter keeps counters and blocks in process memory. It is meant
+// for tests, simulations and single-instance deployments.
+type MemoryRateLimiter struct {
+	Clock clock.Clock
+
+	mu        sync.Mutex
+	counters  map[string]memoryCounter
+	blocks    map[string]time.Time
+	nextSweep time.Time
+}
+
+var (
+	_ repository.RateLimiterRepository = (*MemoryRateLimiter)(nil)
+	_ repository.BatchIncrementer      = (*MemoryRateLimiter)(nil)
+)
+
+func NewMemoryRateLimiter(clk clock.Clock) *MemoryRateLimiter {
+	return &MemoryRateLimiter{
+		Clock:    clk,
+		counters: make(map[string]memoryCounter),
+		blocks:   make(map[string]time.Time),
+	}
+}
+
+func (m *MemoryRateLimiter) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
+	return m.IncrementBy(ctx, key, 1, window)
+}
+
+func (m *MemoryRateLimiter) IncrementBy(ctx context.Context, key string, n int64, window time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.Clock.Now()
+	m.sweep(now, window)
+	c, ok := m.counters[key]
+	if !ok || !now.Before(c.expiresAt) {
+		c = memoryCounter{expiresAt: now.Add(window)}
+	}
+	c.count += n
+	m.counters[key] = c
+	return c.count, nil
+}
+
+func (m *MemoryRateLimiter) Block(ctx context.Context, key string, blockDuration time.Duration) error {
+	if blockDuration <= 0 {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.blocks[key] = m.Clock.Now().Add(blockDuration)
+	return nil
+}
+
+func (m *MemoryRateLimiter) IsBlocked(ctx context.Context, key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return !m.blockedUntil(key).IsZero(), nil
+}
+
+func (m *MemoryRateLimiter) GetState(ctx context.Context, key string) (*entity.RateLimit, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var count int64
+	if c, ok := m.counters[key]; ok && m.Clock.Now().Before(c.expiresAt) {
+		count = c.count
+	}
+
+	return &entity.RateLimit{
+		Key:          key,
+		Count:        count,
+		BlockedUntil: m.blockedUntil(key),
+	}, nil
+}
+
+func (m *MemoryRateLimiter) blockedUntil(key string) time.Time {
+	until, ok := m.blocks[key]
+	if !ok {
+		return time.Time{}
+	}
+	if !m.Clock.Now().Before(until) {
+		delete(m.blocks, key)
+		return time.Time{}
+	}
+	return until
+}
+
+// sweep drops expired counters at most once per window so idle keys do not
+// accumulate forever.
+func (m *MemoryRateLimiter) sweep(now time.Time, window time.Duration) {
+	if now.Before(m.nextSweep) {
+		return
+	}
+	for key, c := range m.counters {
+		if !now.Before(c.expiresAt) {
+			delete(m.counters, key)
+		}
+	}
+	for key, until := range m.blocks {
+		if !now.Before(until) {
+			delete(m.blocks, key)
+		}
+	}
+	m.nextSweep = now.Add(window)
+}
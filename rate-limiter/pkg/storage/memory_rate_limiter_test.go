@@ -0,0 +1,67 @@
+package storage_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/clock"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/storage"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/storage/storagetest"
+)
+
+func TestMemoryIncrementWindow(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	repo := storage.NewMemoryRateLimiter(clk)
+
+	for i := int64(1); i <= 3; i++ {
+		count, err := repo.Increment(context.Background(), "test", time.Second)
+		if err != nil || count != i {
+			t.Fatalf("Expected %d, got %d", i, count)
+		}
+	}
+
+	clk.Advance(time.Second)
+	count, _ := repo.Increment(context.Background(), "test", time.Second)
+	if count != 1 {
+		t.Errorf("Expected reset to 1 after window, got %d", count)
+	}
+}
+
+func TestMemoryBlockAndState(t *testing.T) {
+	start := time.Unix(100, 0)
+	clk := clock.NewFake(start)
+	repo := storage.NewMemoryRateLimiter(clk)
+
+	_, _ = repo.Increment(context.Background(), "test", 20*time.Second)
+	_ = repo.Block(context.Background(), "test", 10*time.Second)
+
+	blocked, err := repo.IsBlocked(context.Background(), "test")
+	if err != nil || !blocked {
+		t.Error("Expected blocked")
+	}
+	state, _ := repo.GetState(context.Background(), "test")
+	if state.Count != 1 || !state.BlockedUntil.Equal(start.Add(10*time.Second)) {
+		t.Errorf("Expected count=1 and exact BlockedUntil: got %d %v", state.Count, state.BlockedUntil)
+	}
+
+	clk.Advance(10 * time.Second)
+	blocked, _ = repo.IsBlocked(context.Background(), "test")
+	state, _ = repo.GetState(context.Background(), "test")
+	if blocked || !state.BlockedUntil.IsZero() || state.Count != 1 {
+		t.Errorf("Expected unblocked with count kept: got blocked=%v state=%+v", blocked, state)
+	}
+
+	clk.Advance(10 * time.Second)
+	state, _ = repo.GetState(context.Background(), "test")
+	if state.Count != 0 {
+		t.Errorf("Expected count to expire, got %d", state.Count)
+	}
+}
+
+func TestMemoryConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Harness {
+		clk := clock.NewFake(time.Unix(0, 0))
+		return storagetest.Harness{Repo: storage.NewMemoryRateLimiter(clk), Now: clk.Now, Advance: clk.Advance}
+	})
+}
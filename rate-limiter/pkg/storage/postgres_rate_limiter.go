@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/entity"
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/repository"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/clock"
+)
+
+const postgresSchema = `
+CREATE TABLE IF NOT EXISTS rate_limits (
+	key           TEXT PRIMARY KEY,
+	count         BIGINT NOT NULL DEFAULT 0,
+	window_start  TIMESTAMPTZ NOT NULL,
+	window_end    TIMESTAMPTZ NOT NULL,
+	blocked_until TIMESTAMPTZ
+)`
+
+// The window resets when the stored one has ended, mirroring INCR+EXPIRE in
+// Redis: the first hit after expiry opens a new window anchored at now.
+const postgresIncrement = `
+INSERT INTO rate_limits (key, count, window_start, window_end)
+VALUES ($1, $4, $2, $3)
+ON CONFLICT (key) DO UPDATE SET
+	count        = CASE WHEN rate_limits.window_end <= $2 THEN $4 ELSE rate_limits.count + $4 END,
+	window_start = CASE WHEN rate_limits.window_end <= $2 THEN $2 ELSE rate_limits.window_start END,
+	window_end   = CASE WHEN rate_limits.window_end <= $2 THEN $3 ELSE rate_limits.window_end END
+RETURNING count`
+
+const postgresBlock = `
+INSERT INTO rate_limits (key, count, window_start, window_end, blocked_until)
+VALUES ($1, 0, $2, $2, $3)
+ON CONFLICT (key) DO UPDATE SET blocked_until = EXCLUDED.blocked_until`
+
+// PostgresRateLimiter stores one row per key. Writes take a transaction-scoped
+// advisory lock on the key so concurrent instances serialize per key without
+// locking the whole table. All timestamps come from Clock, not from now().
+type PostgresRateLimiter struct {
+	Pool  *pgxpool.Pool
+	Clock clock.Clock
+}
+
+var (
+	_ repository.RateLimiterRepository = (*PostgresRateLimiter)(nil)
+	_ repository.BatchIncrementer      = (*PostgresRateLimiter)(nil)
+)
+
+func NewPostgresRateLimiter(ctx context.Context, dsn string) (*PostgresRateLimiter, error) {
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	repo := &PostgresRateLimiter{Pool: pool, Clock: clock.Real{}}
+	if err := repo.Migrate(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return repo, nil
+}
+
+func (p *PostgresRateLimiter) Migrate(ctx context.Context) error {
+	_, err := p.Pool.Exec(ctx, postgresSchema)
+	return err
+}
+
+func (p *PostgresRateLimiter) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
+	return p.IncrementBy(ctx, key, 1, window)
+}
+
+func (p *PostgresRateLimiter) IncrementBy(ctx context.Context, key string, n int64, window time.Duration) (int64, error) {
+	now := p.Clock.Now()
+	var count int64
+	err := p.withKeyLock(ctx, key, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, postgresIncrement, key, now, now.Add(window), n).Scan(&count)
+	})
+	return count, err
+}
+
+func (p *PostgresRateLimiter) Block(ctx context.Context, key string, blockDuration time.Duration) error {
+	if blockDuration <= 0 {
+		return nil
+	}
+	now := p.Clock.Now()
+	return p.withKeyLock(ctx, key, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, postgresBlock, key, now, now.Add(blockDuration))
+		return err
+	})
+}
+
+func (p *PostgresRateLimiter) IsBlocked(ctx context.Context, key string) (bool, error) {
+	var blocked bool
+	err := p.Pool.QueryRow(ctx,
+		`SELECT COALESCE(blocked_until > $2, false) FROM rate_limits WHERE key = $1`,
+		key, p.Clock.Now(),
+	).Scan(&blocked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return blocked, err
+}
+
+func (p *PostgresRateLimiter) GetState(ctx context.Context, key string) (*entity.RateLimit, error) {
+	state := &entity.RateLimit{Key: key}
+	now := p.Clock.Now()
+
+	var count int64
+	var windowEnd time.Time
+	var blockedUntil *time.Time
+	err := p.Pool.QueryRow(ctx,
+		`SELECT count, window_end, blocked_until FROM rate_limits WHERE key = $1`, key,
+	).Scan(&count, &windowEnd, &blockedUntil)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return state, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if windowEnd.After(now) {
+		state.Count = count
+	}
+	if blockedUntil != nil && blockedUntil.After(now) {
+		state.BlockedUntil = *blockedUntil
+	}
+	return state, nil
+}
+
+// Purge deletes rows whose window and block have both expired.
+func (p *PostgresRateLimiter) Purge(ctx context.Context) error {
+	_, err := p.Pool.Exec(ctx,
+		`DELETE FROM rate_limits WHERE window_end <= $1 AND (blocked_until IS NULL OR blocked_until <= $1)`,
+		p.Clock.Now(),
+	)
+	return err
+}
+
+func (p *PostgresRateLimiter) withKeyLock(ctx context.Context, key string, fn func(tx pgx.Tx) error) error {
+	return pgx.BeginFunc(ctx, p.Pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1, 0))`, key); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
@@ -7,35 +7,48 @@ import (
 
 	"github.com/jpfigueredo/rate-limiter-challenge/internal/entity"
 	"github.com/jpfigueredo/rate-limiter-challenge/internal/repository"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/clock"
 	"github.com/redis/go-redis/v9"
 )
 
 type RedisRateLimiter struct {
 	Client *redis.Client
+	Clock  clock.Clock
 }
 
-var _ repository.RateLimiterRepository = (*RedisRateLimiter)(nil)
+var (
+	_ repository.RateLimiterRepository = (*RedisRateLimiter)(nil)
+	_ repository.BatchIncrementer      = (*RedisRateLimiter)(nil)
+)
 
 func NewRedisRateLimiter(addr string) *RedisRateLimiter {
 	client := redis.NewClient(&redis.Options{
 		Addr: addr,
 	})
-	return &RedisRateLimiter{Client: client}
+	return &RedisRateLimiter{Client: client, Clock: clock.Real{}}
 }
 
 func (r *RedisRateLimiter) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
+	return r.IncrementBy(ctx, key, 1, window)
+}
+
+func (r *RedisRateLimiter) IncrementBy(ctx context.Context, key string, n int64, window time.Duration) (int64, error) {
 	rateKey := fmt.Sprintf("rate:%s", key)
-	val, err := r.Client.Incr(ctx, rateKey).Result()
+	val, err := r.Client.IncrBy(ctx, rateKey, n).Result()
 	if err != nil {
 		return 0, err
 	}
-	if val == 1 {
+	if val == n {
 		r.Client.Expire(ctx, rateKey, window)
 	}
 	return val, nil
 }
 
 func (r *RedisRateLimiter) Block(ctx context.Context, key string, blockDuration time.Duration) error {
+	// SET with no expiration would block the key forever.
+	if blockDuration <= 0 {
+		return nil
+	}
 	blockKey := fmt.Sprintf("block:%s", key)
 	return r.Client.Set(ctx, blockKey, "blocked", blockDuration).Err()
 }
@@ -68,7 +81,7 @@ func (r *RedisRateLimiter) GetState(ctx context.Context, key string) (*entity.Ra
 	}
 	blockedUntil := time.Time{}
 	if ttl > 0 {
-		blockedUntil = time.Now().Add(ttl)
+		blockedUntil = r.now().Add(ttl)
 	}
 
 	return &entity.RateLimit{
@@ -77,3 +90,10 @@ func (r *RedisRateLimiter) GetState(ctx context.Context, key string) (*entity.Ra
 		BlockedUntil: blockedUntil,
 	}, nil
 }
+
+func (r *RedisRateLimiter) now() time.Time {
+	if r.Clock == nil {
+		return time.Now()
+	}
+	return r.Clock.Now()
+}
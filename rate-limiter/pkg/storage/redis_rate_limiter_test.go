@@ -6,14 +6,26 @@ import (
 	"time"
 
 	"github.com/alicebob/miniredis/v2"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/clock"
 	"github.com/jpfigueredo/rate-limiter-challenge/pkg/storage"
+	"github.com/jpfigueredo/rate-limiter-challenge/pkg/storage/storagetest"
 	"github.com/redis/go-redis/v9"
 )
 
-func TestIncrementMultiple(t *testing.T) {
+// newFakeRedis drives the repository and miniredis TTLs from one fake clock.
+// miniredis expires keys on its own clock, so advance moves both.
+func newFakeRedis(t *testing.T) (*storage.RedisRateLimiter, *clock.Fake, func(time.Duration)) {
 	mr := miniredis.RunT(t)
-	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
-	repo := &storage.RedisRateLimiter{Client: client}
+	clk := clock.NewFake(time.Unix(1000, 0))
+	repo := &storage.RedisRateLimiter{Client: redis.NewClient(&redis.Options{Addr: mr.Addr()}), Clock: clk}
+	return repo, clk, func(d time.Duration) {
+		clk.Advance(d)
+		mr.FastForward(d)
+	}
+}
+
+func TestIncrementMultiple(t *testing.T) {
+	repo, _, advance := newFakeRedis(t)
 
 	count, err := repo.Increment(context.Background(), "test", time.Second)
 	if err != nil || count != 1 {
@@ -25,7 +37,7 @@ func TestIncrementMultiple(t *testing.T) {
 		t.Error("Expected 2 on second")
 	}
 
-	mr.FastForward(time.Second + time.Millisecond)
+	advance(time.Second + time.Millisecond)
 	count, err = repo.Increment(context.Background(), "test", time.Second)
 	if err != nil || count != 1 {
 		t.Error("Expected reset to 1 after expiration")
@@ -33,9 +45,7 @@ func TestIncrementMultiple(t *testing.T) {
 }
 
 func TestBlockAndIsBlocked(t *testing.T) {
-	mr := miniredis.RunT(t)
-	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
-	repo := &storage.RedisRateLimiter{Client: client}
+	repo, _, advance := newFakeRedis(t)
 
 	err := repo.Block(context.Background(), "test", 10*time.Second)
 	if err != nil {
@@ -47,7 +57,7 @@ func TestBlockAndIsBlocked(t *testing.T) {
 		t.Error("Expected blocked")
 	}
 
-	mr.FastForward(11 * time.Second)
+	advance(11 * time.Second)
 	blocked, err = repo.IsBlocked(context.Background(), "test")
 	if err != nil || blocked {
 		t.Error("Expected not blocked after expiration")
@@ -102,12 +112,10 @@ func TestIsBlockedError(t *testing.T) {
 }
 
 func TestIncrementPartialExpiration(t *testing.T) {
-	mr := miniredis.RunT(t)
-	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
-	repo := &storage.RedisRateLimiter{Client: client}
+	repo, _, advance := newFakeRedis(t)
 
 	_, _ = repo.Increment(context.Background(), "test", 10*time.Second)
-	mr.FastForward(5 * time.Second)
+	advance(5 * time.Second)
 	count, err := repo.Increment(context.Background(), "test", 10*time.Second)
 	if err != nil || count != 2 {
 		t.Error("Expected count 2 after partial time")
@@ -115,19 +123,18 @@ func TestIncrementPartialExpiration(t *testing.T) {
 }
 
 func TestGetStateFull(t *testing.T) {
-	mr := miniredis.RunT(t)
-	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
-	repo := &storage.RedisRateLimiter{Client: client}
+	repo, clk, advance := newFakeRedis(t)
+	start := clk.Now()
 
 	_, _ = repo.Increment(context.Background(), "test", 20*time.Second)
 	_ = repo.Block(context.Background(), "test", 10*time.Second)
 
 	state, err := repo.GetState(context.Background(), "test")
-	if err != nil || state.Count != 1 || state.BlockedUntil.IsZero() {
-		t.Errorf("Expected count=1 and BlockedUntil not zero: got Count=%d, BlockedUntil=%v", state.Count, state.BlockedUntil)
+	if err != nil || state.Count != 1 || !state.BlockedUntil.Equal(start.Add(10*time.Second)) {
+		t.Errorf("Expected count=1 and BlockedUntil at block expiry: got Count=%d, BlockedUntil=%v", state.Count, state.BlockedUntil)
 	}
 
-	mr.FastForward(11 * time.Second)
+	advance(11 * time.Second)
 	state, err = repo.GetState(context.Background(), "test")
 	if err != nil || state.Count != 1 || !state.BlockedUntil.IsZero() {
 		t.Errorf("Expected count=1 persist and BlockedUntil zero: got Count=%d, BlockedUntil=%v", state.Count, state.BlockedUntil)
@@ -145,3 +152,10 @@ func TestGetStateError(t *testing.T) {
 		t.Error("Expected error on getstate with closed client")
 	}
 }
+
+func TestRedisConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Harness {
+		repo, clk, advance := newFakeRedis(t)
+		return storagetest.Harness{Repo: repo, Now: clk.Now, Advance: advance}
+	})
+}
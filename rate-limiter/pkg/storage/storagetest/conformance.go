@@ -0,0 +1,226 @@
+// Package storagetest holds the conformance suite every
+// repository.RateLimiterRepository implementation must pass.
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jpfigueredo/rate-limiter-challenge/internal/repository"
+)
+
+const (
+	Window        = time.Second
+	BlockDuration = 2 * time.Second
+)
+
+// Harness is a fresh backend for one test. Advance moves time forward by at
+// least d for everything the backend uses to expire keys; backends with server
+// side expiration may sleep for longer than d.
+type Harness struct {
+	Repo    repository.RateLimiterRepository
+	Now     func() time.Time
+	Advance func(d time.Duration)
+}
+
+func Run(t *testing.T, newHarness func(t *testing.T) Harness) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, h Harness)
+	}{
+		{"IncrementCounts", testIncrementCounts},
+		{"IncrementResetsAfterWindow", testIncrementResetsAfterWindow},
+		{"IncrementKeepsWindowOpen", testIncrementKeepsWindowOpen},
+		{"IncrementByCountsHits", testIncrementByCountsHits},
+		{"KeysAreIndependent", testKeysAreIndependent},
+		{"BlockExpires", testBlockExpires},
+		{"ZeroBlockDoesNotBlock", testZeroBlockDoesNotBlock},
+		{"StateOfUnknownKey", testStateOfUnknownKey},
+		{"StateReportsCountAndBlock", testStateReportsCountAndBlock},
+		{"ConcurrentIncrements", testConcurrentIncrements},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fn(t, newHarness(t))
+		})
+	}
+}
+
+func key(t *testing.T) string {
+	return fmt.Sprintf("conformance:%s:%d", t.Name(), time.Now().UnixNano())
+}
+
+func increment(t *testing.T, h Harness, k string) int64 {
+	t.Helper()
+	count, err := h.Repo.Increment(context.Background(), k, Window)
+	if err != nil {
+		t.Fatalf("Expected increment to succeed: %v", err)
+	}
+	return count
+}
+
+func testIncrementCounts(t *testing.T, h Harness) {
+	k := key(t)
+	for i := int64(1); i <= 3; i++ {
+		if count := increment(t, h, k); count != i {
+			t.Fatalf("Expected count %d, got %d", i, count)
+		}
+	}
+}
+
+func testIncrementResetsAfterWindow(t *testing.T, h Harness) {
+	k := key(t)
+	increment(t, h, k)
+	increment(t, h, k)
+
+	h.Advance(Window)
+	if count := increment(t, h, k); count != 1 {
+		t.Errorf("Expected reset to 1 after window, got %d", count)
+	}
+}
+
+func testIncrementKeepsWindowOpen(t *testing.T, h Harness) {
+	k := key(t)
+	if _, err := h.Repo.Increment(context.Background(), k, 10*Window); err != nil {
+		t.Fatalf("Expected increment to succeed: %v", err)
+	}
+	h.Advance(Window)
+	count, err := h.Repo.Increment(context.Background(), k, 10*Window)
+	if err != nil || count != 2 {
+		t.Errorf("Expected count 2 inside a long window, got %d %v", count, err)
+	}
+}
+
+func testIncrementByCountsHits(t *testing.T, h Harness) {
+	b, ok := h.Repo.(repository.BatchIncrementer)
+	if !ok {
+		t.Skip("repository does not implement BatchIncrementer")
+	}
+	k := key(t)
+	if count, err := b.IncrementBy(context.Background(), k, 3, Window); err != nil || count != 3 {
+		t.Fatalf("Expected count 3 on a new key, got %d %v", count, err)
+	}
+	if count, err := b.IncrementBy(context.Background(), k, 4, Window); err != nil || count != 7 {
+		t.Errorf("Expected count 7, got %d %v", count, err)
+	}
+	if count := increment(t, h, k); count != 8 {
+		t.Errorf("Expected Increment to share the counter, got %d", count)
+	}
+
+	h.Advance(Window)
+	if count, err := b.IncrementBy(context.Background(), k, 2, Window); err != nil || count != 2 {
+		t.Errorf("Expected reset to 2 after window, got %d %v", count, err)
+	}
+}
+
+func testKeysAreIndependent(t *testing.T, h Harness) {
+	a, b := key(t)+":a", key(t)+":b"
+	increment(t, h, a)
+	increment(t, h, a)
+	if count := increment(t, h, b); count != 1 {
+		t.Errorf("Expected independent counter, got %d", count)
+	}
+
+	if err := h.Repo.Block(context.Background(), a, BlockDuration); err != nil {
+		t.Fatalf("Expected block to succeed: %v", err)
+	}
+	if blocked, err := h.Repo.IsBlocked(context.Background(), b); err != nil || blocked {
+		t.Errorf("Expected other key not blocked, got %v %v", blocked, err)
+	}
+}
+
+func testBlockExpires(t *testing.T, h Harness) {
+	k := key(t)
+	if blocked, err := h.Repo.IsBlocked(context.Background(), k); err != nil || blocked {
+		t.Fatalf("Expected unknown key not blocked, got %v %v", blocked, err)
+	}
+	if err := h.Repo.Block(context.Background(), k, BlockDuration); err != nil {
+		t.Fatalf("Expected block to succeed: %v", err)
+	}
+	if blocked, err := h.Repo.IsBlocked(context.Background(), k); err != nil || !blocked {
+		t.Fatalf("Expected blocked, got %v %v", blocked, err)
+	}
+
+	h.Advance(BlockDuration)
+	if blocked, err := h.Repo.IsBlocked(context.Background(), k); err != nil || blocked {
+		t.Errorf("Expected block to expire, got %v %v", blocked, err)
+	}
+}
+
+func testZeroBlockDoesNotBlock(t *testing.T, h Harness) {
+	k := key(t)
+	if err := h.Repo.Block(context.Background(), k, 0); err != nil {
+		t.Fatalf("Expected zero block to succeed: %v", err)
+	}
+	if blocked, err := h.Repo.IsBlocked(context.Background(), k); err != nil || blocked {
+		t.Errorf("Expected zero block not to block, got %v %v", blocked, err)
+	}
+	state, err := h.Repo.GetState(context.Background(), k)
+	if err != nil || !state.BlockedUntil.IsZero() {
+		t.Errorf("Expected no BlockedUntil after zero block, got %+v %v", state, err)
+	}
+}
+
+func testStateOfUnknownKey(t *testing.T, h Harness) {
+	k := key(t)
+	state, err := h.Repo.GetState(context.Background(), k)
+	if err != nil || state.Key != k || state.Count != 0 || !state.BlockedUntil.IsZero() {
+		t.Errorf("Expected zero state, got %+v %v", state, err)
+	}
+}
+
+func testStateReportsCountAndBlock(t *testing.T, h Harness) {
+	k := key(t)
+	increment(t, h, k)
+	increment(t, h, k)
+	before := h.Now()
+	if err := h.Repo.Block(context.Background(), k, BlockDuration); err != nil {
+		t.Fatalf("Expected block to succeed: %v", err)
+	}
+
+	state, err := h.Repo.GetState(context.Background(), k)
+	if err != nil {
+		t.Fatalf("Expected state: %v", err)
+	}
+	if state.Count != 2 {
+		t.Errorf("Expected count 2, got %d", state.Count)
+	}
+	if state.BlockedUntil.Before(before) || state.BlockedUntil.After(h.Now().Add(BlockDuration)) {
+		t.Errorf("Expected BlockedUntil within block duration, got %v (now %v)", state.BlockedUntil, h.Now())
+	}
+
+	h.Advance(BlockDuration)
+	state, err = h.Repo.GetState(context.Background(), k)
+	if err != nil || state.Count != 0 || !state.BlockedUntil.IsZero() {
+		t.Errorf("Expected expired state, got %+v %v", state, err)
+	}
+}
+
+func testConcurrentIncrements(t *testing.T, h Harness) {
+	k := key(t)
+	const n = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := h.Repo.Increment(context.Background(), k, 10*Window); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("Expected concurrent increment to succeed: %v", err)
+	}
+
+	state, err := h.Repo.GetState(context.Background(), k)
+	if err != nil || state.Count != n {
+		t.Errorf("Expected %d after concurrent increments, got %+v %v", n, state, err)
+	}
+}
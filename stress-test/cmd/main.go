@@ -1,56 +1,531 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"net"
 	"os"
+	"os/signal"
+	"strings"
 	"sync"
 	"time"
 
 	"stress-test/internal"
 )
 
-func main() {
-	url := flag.String("url", "", "Target URL to test")
-	requests := flag.Int("requests", 1, "Total number of requests to perform")
-	concurrency := flag.Int("concurrency", 1, "Number of concurrent workers")
-	output := flag.String("output", "default", "Output format: default, json, csv")
-	detailed := flag.Bool("detailed", false, "Include detailed latencies and histogram in output")
-	flag.Parse()
+type options struct {
+	url              string
+	requests         int
+	concurrency      int
+	output           string
+	detailed         bool
+	method           string
+	headers          internal.HeaderFlags
+	body             string
+	bodyFile         string
+	contentType      string
+	duration         time.Duration
+	rate             float64
+	stages           []internal.Stage
+	scenarioFile     string
+	progress         bool
+	stream           string
+	interval         time.Duration
+	thresholds       internal.ThresholdFlags
+	client           internal.ClientOptions
+	grpcMethod       string
+	protoFiles       internal.ListFlags
+	importPaths      internal.ListFlags
+	protoset         string
+	graphqlQuery     string
+	graphqlVariables string
+	graphqlOperation string
+	assert           *internal.Assertions
+	feeder           string
+	feederMode       string
+	feederPartition  bool
+	agent, agents    int
+	htmlFile         string
+	listen           string
+	agentTimeout     time.Duration
+}
 
-	if *url == "" {
-		fmt.Println("Error: --url is required")
-		os.Exit(1)
+func parseOptions(name string, args []string, handling flag.ErrorHandling) (*options, error) {
+	o := &options{}
+	fs := flag.NewFlagSet(name, handling)
+	fs.StringVar(&o.url, "url", "", "Target URL to test")
+	fs.IntVar(&o.requests, "requests", 1, "Total number of requests to perform (iterations with --scenario)")
+	fs.IntVar(&o.concurrency, "concurrency", 1, "Number of concurrent workers")
+	fs.StringVar(&o.output, "output", "default", "Output format: default, json, csv")
+	fs.BoolVar(&o.detailed, "detailed", false, "Include the latency histogram in output")
+	fs.StringVar(&o.method, "method", "GET", "HTTP method")
+	fs.Var(&o.headers, "H", "Request header \"Name: value\" (repeatable)")
+	fs.StringVar(&o.body, "body", "", "Request body, or @file to read it from a file")
+	fs.StringVar(&o.bodyFile, "body-file", "", "File containing the request body")
+	fs.StringVar(&o.contentType, "content-type", "", "Content-Type header for the request body")
+	fs.DurationVar(&o.duration, "duration", 0, "Run for this long instead of a fixed number of requests")
+	fs.Float64Var(&o.rate, "rate", 0, "Requests per second on a fixed schedule (open model); --concurrency caps in-flight requests")
+	stagesFlag := fs.String("stages", "", "Rate ramp stages as rate:duration, e.g. 500:60s,500:2m (starts from --rate)")
+	fs.StringVar(&o.scenarioFile, "scenario", "", "YAML or JSON scenario file with multi-step flows; --url becomes the base URL")
+	fs.BoolVar(&o.progress, "progress", true, "Show live progress on the terminal")
+	fs.StringVar(&o.stream, "stream", "", "Append per-interval snapshots as JSON lines to this file")
+	fs.DurationVar(&o.interval, "interval", time.Second, "Progress and stream snapshot interval")
+	fs.Var(&o.thresholds, "threshold", "Pass/fail condition such as p95<200ms, error_rate<1% or rps>500 (repeatable)")
+	thresholdsFile := fs.String("thresholds-file", "", "File with one threshold per line")
+	fs.DurationVar(&o.client.Timeout, "timeout", 30*time.Second, "Request timeout (0 disables it)")
+	fs.BoolVar(&o.client.KeepAlive, "keepalive", true, "Reuse connections between requests")
+	fs.IntVar(&o.client.MaxIdleConns, "max-idle-conns", 0, "Idle connections kept per host (defaults to --concurrency)")
+	fs.BoolVar(&o.client.HTTP2, "http2", true, "Allow HTTP/2 over TLS")
+	fs.BoolVar(&o.client.Insecure, "insecure", false, "Skip TLS certificate verification")
+	fs.StringVar(&o.client.CertFile, "cert", "", "Client certificate file (PEM)")
+	fs.StringVar(&o.client.KeyFile, "key", "", "Client certificate key file (PEM)")
+	fs.StringVar(&o.client.Proxy, "proxy", "", "Proxy URL (defaults to the HTTP_PROXY/HTTPS_PROXY environment)")
+	fs.StringVar(&o.grpcMethod, "grpc-method", "", "Load test a unary gRPC method (package.Service/Method) at --url host:port; --body is the JSON request")
+	fs.Var(&o.protoFiles, "proto", "Proto file describing the gRPC service (repeatable; default: server reflection)")
+	fs.Var(&o.importPaths, "import-path", "Import path for --proto files (repeatable)")
+	fs.StringVar(&o.protoset, "protoset", "", "Compiled FileDescriptorSet describing the gRPC service")
+	fs.StringVar(&o.graphqlQuery, "graphql-query", "", "Load test a GraphQL endpoint at --url with this query, or @file to read it from a file")
+	fs.StringVar(&o.graphqlVariables, "graphql-variables", "", "JSON object with the GraphQL query variables")
+	fs.StringVar(&o.graphqlOperation, "graphql-operation", "", "GraphQL operation name to run from the query document")
+	var expect internal.Assertions
+	var expectStatus, expectBody, expectRegex, expectJSON internal.ListFlags
+	fs.Var(&expectStatus, "expect-status", "Accepted status codes or ranges, e.g. 200-299,304 (replaces the default status check)")
+	fs.Var(&expectBody, "expect-body", "Substring every response body must contain (repeatable)")
+	fs.Var(&expectRegex, "expect-regex", "Regular expression every response body must match (repeatable)")
+	fs.Var(&expectJSON, "expect-json", "JSON path that must exist, or path=value it must equal, e.g. data.status=PAID (repeatable)")
+	fs.IntVar(&expect.MaxSize, "max-size", 0, "Maximum response body size in bytes")
+	fs.StringVar(&o.feeder, "feeder", "", "CSV (with a header row) or JSONL file whose rows feed URL, header and body templates, e.g. {{.cep}}")
+	fs.StringVar(&o.feederMode, "feeder-mode", "circular", "How rows are used: sequential (each once, then stop), circular or random")
+	fs.BoolVar(&o.feederPartition, "feeder-partition", false, "Give each worker its own share of the feeder rows")
+	fs.StringVar(&o.htmlFile, "html", "", "Also write a self-contained HTML report with charts to this file")
+	fs.StringVar(&o.listen, "listen", ":7000", "Address the coordinator listens on for agents")
+	fs.IntVar(&o.agents, "agents", 0, "Run as coordinator and split the load across this many agents (files such as --scenario must exist on every agent)")
+	fs.DurationVar(&o.agentTimeout, "agent-timeout", internal.DefaultAgentTimeout, "Stop waiting for an agent that sent no update for this long")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
 	}
 
-	results := make(chan internal.Result, *requests)
-	var wg sync.WaitGroup
+	expect.Status, expect.Contains, expect.Matches = expectStatus, expectBody, expectRegex
+	for _, entry := range expectJSON {
+		if expect.JSON == nil {
+			expect.JSON = make(map[string]string)
+		}
+		path, value := internal.ParseJSONAssertion(entry)
+		expect.JSON[path] = value
+	}
+	var err error
+	if o.assert, err = internal.NewAssertions(expect); err != nil {
+		return nil, err
+	}
+	if o.assert != nil && o.grpcMethod != "" {
+		return nil, fmt.Errorf("--expect-* and --max-size check HTTP responses and cannot be used with --grpc-method")
+	}
+	if o.stages, err = internal.ParseStages(*stagesFlag); err != nil {
+		return nil, err
+	}
+	if (o.rate > 0 || len(o.stages) > 0) && o.duration == 0 && len(o.stages) == 0 {
+		return nil, fmt.Errorf("--rate requires --duration or --stages")
+	}
+	if *thresholdsFile != "" {
+		fromFile, err := internal.LoadThresholds(*thresholdsFile)
+		if err != nil {
+			return nil, err
+		}
+		o.thresholds = append(o.thresholds, fromFile...)
+	}
+	return o, nil
+}
 
-	start := time.Now()
-	for i := 0; i < *concurrency; i++ {
+// share narrows the options to one agent's part of the load.
+func (o *options) share(plan internal.Plan) {
+	o.agent, o.agents = plan.Agent, plan.Agents
+	o.requests = plan.Share(o.requests)
+	o.concurrency = max(plan.Share(o.concurrency), 1)
+	o.rate = plan.ShareRate(o.rate)
+	for i := range o.stages {
+		o.stages[i].Target = plan.ShareRate(o.stages[i].Target)
+	}
+}
+
+// loadFeeder reads --feeder, narrowed to this agent's rows in a distributed
+// run. It returns nil without --feeder.
+func (o *options) loadFeeder() (*internal.Feeder, error) {
+	if o.feeder == "" {
+		return nil, nil
+	}
+	feeder, err := internal.LoadFeeder(o.feeder, internal.FeedMode(o.feederMode))
+	if err != nil {
+		return nil, err
+	}
+	if o.agents > 1 {
+		feeder.Slice(o.agent, o.agents)
+	}
+	if o.feederPartition {
+		feeder.Workers = o.concurrency
+	}
+	return feeder, nil
+}
+
+// executor builds what every worker runs; the returned function releases
+// its resources.
+func (o *options) executor() (internal.Executor, internal.Protocol, func(), error) {
+	nothing := func() {}
+	feeder, err := o.loadFeeder()
+	if err != nil {
+		return nil, "", nothing, err
+	}
+	switch {
+	case o.scenarioFile != "":
+		scenario, err := internal.LoadScenario(o.scenarioFile, o.url, o.assert)
+		if err != nil {
+			return nil, "", nothing, err
+		}
+		scenario.Feeder = feeder
+		return scenario, internal.ProtocolHTTP, nothing, nil
+	case o.url == "":
+		return nil, "", nothing, fmt.Errorf("--url is required")
+	case feeder != nil && (o.grpcMethod != "" || o.graphqlQuery != ""):
+		return nil, "", nothing, fmt.Errorf("--feeder works with HTTP requests and scenarios")
+	case o.grpcMethod != "":
+		payload, err := internal.LoadBody(o.body, o.bodyFile)
+		if err != nil {
+			return nil, "", nothing, err
+		}
+		spec, err := internal.NewGRPCSpec(context.Background(), internal.GRPCOptions{
+			Target:      o.url,
+			Method:      o.grpcMethod,
+			ProtoFiles:  o.protoFiles,
+			ImportPaths: o.importPaths,
+			Protoset:    o.protoset,
+			Body:        payload,
+			Headers:     o.headers.Header(),
+			Insecure:    o.client.Insecure,
+			Timeout:     o.client.Timeout,
+		})
+		if err != nil {
+			return nil, "", nothing, err
+		}
+		return spec, internal.ProtocolGRPC, func() { spec.Close() }, nil
+	case o.graphqlQuery != "":
+		query, err := internal.LoadBody(o.graphqlQuery, "")
+		if err != nil {
+			return nil, "", nothing, err
+		}
+		spec, err := internal.NewGraphQLSpec(o.url, string(query), o.graphqlOperation, []byte(o.graphqlVariables), o.headers.Header(), o.assert)
+		return spec, internal.ProtocolGraphQL, nothing, err
+	default:
+		payload, err := internal.LoadBody(o.body, o.bodyFile)
+		if err != nil {
+			return nil, "", nothing, err
+		}
+		spec := internal.RequestSpec{
+			Method:  strings.ToUpper(o.method),
+			URL:     o.url,
+			Headers: o.headers.Header(),
+			Body:    payload,
+			Assert:  o.assert,
+		}
+		if o.contentType != "" {
+			spec.Headers.Set("Content-Type", o.contentType)
+		}
+		if _, err := spec.NewRequest(); err != nil {
+			return nil, "", nothing, err
+		}
+		if feeder != nil {
+			scenario, err := templated(spec, feeder)
+			return scenario, internal.ProtocolHTTP, nothing, err
+		}
+		return spec, internal.ProtocolHTTP, nothing, nil
+	}
+}
+
+// templated turns spec into a one-step scenario, so its URL, headers and
+// body are rendered with every feeder row.
+func templated(spec internal.RequestSpec, feeder *internal.Feeder) (*internal.Scenario, error) {
+	headers := make(map[string]string, len(spec.Headers))
+	for name, values := range spec.Headers {
+		headers[name] = strings.Join(values, ", ")
+	}
+	step := internal.Step{
+		Method:  spec.Method,
+		URL:     spec.URL,
+		Headers: headers,
+		Body:    string(spec.Body),
+		Expect:  spec.Assert,
+	}
+	return internal.NewScenario(&internal.Scenario{Steps: []internal.Step{step}, Feeder: feeder}, "", spec.Assert)
+}
+
+// run generates the load, closing results when every worker is done.
+func (o *options) run(exec internal.Executor, results chan<- internal.Result) error {
+	client, err := internal.NewHTTPClient(o.client)
+	if err != nil {
+		return err
+	}
+	defer close(results)
+
+	if o.rate > 0 || len(o.stages) > 0 {
+		schedule := internal.RateSchedule{Start: o.rate, Stages: o.stages, Duration: o.duration}
+		internal.OpenLoad(exec, schedule, o.concurrency, client, results)
+		return nil
+	}
+
+	var deadline time.Time
+	if o.duration > 0 {
+		deadline = time.Now().Add(o.duration)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < o.concurrency; i++ {
 		wg.Add(1)
-		reqs := *requests / *concurrency
-		if i < *requests%*concurrency {
+		reqs := o.requests / o.concurrency
+		if i < o.requests%o.concurrency {
 			reqs++
 		}
-		go internal.Worker(*url, reqs, results, &wg)
+		go internal.Worker(exec, internal.NewVirtualUser(i, client), reqs, deadline, results, &wg)
 	}
-
 	wg.Wait()
-	close(results)
+	return nil
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "agent":
+			runAgent(os.Args[2:])
+			return
+		case "compare":
+			runCompare(os.Args[2:])
+			return
+		}
+	}
+
+	opts, err := parseOptions(os.Args[0], os.Args[1:], flag.ExitOnError)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	if opts.client.MaxIdleConns == 0 {
+		opts.client.MaxIdleConns = opts.concurrency
+	}
+
+	var sinks []func(internal.Snapshot)
+	if opts.progress && internal.IsTerminal(os.Stderr) {
+		sinks = append(sinks, internal.PrintProgress(os.Stderr))
+	}
+	if opts.stream != "" {
+		f, err := os.OpenFile(opts.stream, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		sinks = append(sinks, internal.StreamJSON(f))
+	}
 
-	var allResults []internal.Result
-	for r := range results {
-		allResults = append(allResults, r)
+	recorder := internal.NewRecorder()
+	if opts.assert != nil {
+		recorder.Accepted = strings.Join(opts.assert.Status, ",")
 	}
+	var totalDuration time.Duration
+	if opts.agents > 0 {
+		totalDuration = coordinate(opts, recorder, sinks)
+	} else {
+		exec, protocol, closeExec, err := opts.executor()
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		defer closeExec()
+		recorder.Protocol = protocol
+
+		results := make(chan internal.Result, 1024)
+		collected := make(chan struct{})
+		go func() {
+			internal.Collect(results, recorder, opts.interval, sinks...)
+			close(collected)
+		}()
 
-	totalDuration := time.Since(start)
-	report := internal.CalculateReport(allResults, totalDuration, *detailed)
+		start := time.Now()
+		if err := opts.run(exec, results); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		<-collected
+		totalDuration = time.Since(start)
+	}
 
-	if *detailed && len(report.Latencies) > 0 {
-		hist, _ := internal.BuildHistogram(report.Latencies, 100*time.Millisecond)
-		report.Histogram = hist
+	report := recorder.Report(totalDuration, opts.detailed)
+	passed := true
+	if len(opts.thresholds) > 0 {
+		report.Thresholds, passed = internal.CheckThresholds(report, opts.thresholds)
 	}
 
-	internal.PrintReport(report, *output)
+	internal.PrintReport(report, opts.output)
+
+	if opts.htmlFile != "" {
+		f, err := os.Create(opts.htmlFile)
+		if err == nil {
+			err = internal.WriteHTML(f, report)
+			f.Close()
+		}
+		if err != nil {
+			fmt.Println("Error writing HTML report:", err)
+			os.Exit(1)
+		}
+	}
+
+	if !passed {
+		for _, t := range report.Thresholds {
+			if !t.Passed {
+				fmt.Fprintf(os.Stderr, "threshold failed: %s (actual %s)\n", t.Expr, t.Actual)
+			}
+		}
+		os.Exit(2)
+	}
+}
+
+// coordinatorFlags only make sense on the coordinator. Thresholds are checked
+// against the merged report, so agents never see them either.
+var coordinatorFlags = []string{"listen", "agents", "agent-timeout", "threshold", "thresholds-file"}
+
+// coordinate waits for the agents, hands them the command line without the
+// coordinator flags and merges what they report into recorder.
+func coordinate(opts *options, recorder *internal.Recorder, sinks []func(internal.Snapshot)) time.Duration {
+	protocol := internal.ProtocolHTTP
+	switch {
+	case opts.grpcMethod != "":
+		protocol = internal.ProtocolGRPC
+	case opts.graphqlQuery != "":
+		protocol = internal.ProtocolGraphQL
+	}
+	recorder.Protocol = protocol
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ln, err := net.Listen("tcp", opts.listen)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	args := withoutFlags(os.Args[1:], coordinatorFlags...)
+	coordinator := internal.NewCoordinator(opts.agents, args, opts.interval)
+	coordinator.Timeout = opts.agentTimeout
+	fmt.Fprintf(os.Stderr, "waiting for %d agents on %s\n", opts.agents, opts.listen)
+	duration, err := coordinator.Run(ctx, ln, recorder, sinks...)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	return duration
+}
+
+// withoutFlags drops the named flags and their values from args.
+func withoutFlags(args []string, names ...string) []string {
+	var kept []string
+	for i := 0; i < len(args); i++ {
+		name := strings.TrimLeft(args[i], "-")
+		name, _, hasValue := strings.Cut(name, "=")
+		drop := false
+		for _, n := range names {
+			if strings.HasPrefix(args[i], "-") && name == n {
+				drop = true
+			}
+		}
+		if !drop {
+			kept = append(kept, args[i])
+		} else if !hasValue {
+			i++
+		}
+	}
+	return kept
+}
+
+// runAgent joins a coordinator and runs this agent's share of the plan,
+// streaming what it records back every interval.
+func runAgent(args []string) {
+	fs := flag.NewFlagSet("agent", flag.ExitOnError)
+	coordinator := fs.String("coordinator", "", "Coordinator URL, e.g. http://10.0.0.1:7000")
+	fs.Parse(args)
+	if *coordinator == "" {
+		fmt.Println("Error: --coordinator is required")
+		os.Exit(1)
+	}
+	*coordinator = strings.TrimSuffix(*coordinator, "/")
+
+	plan, err := internal.Join(context.Background(), *coordinator)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "joined as agent %d of %d\n", plan.Agent+1, plan.Agents)
+
+	fail := func(err error) {
+		internal.SendUpdate(*coordinator, internal.AgentUpdate{Agent: plan.Agent, Final: true, Error: err.Error()})
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	opts, err := parseOptions("agent", plan.Args, flag.ContinueOnError)
+	if err != nil {
+		fail(err)
+	}
+	opts.share(plan)
+	if opts.client.MaxIdleConns == 0 {
+		opts.client.MaxIdleConns = opts.concurrency
+	}
+	exec, protocol, closeExec, err := opts.executor()
+	if err != nil {
+		fail(err)
+	}
+	defer closeExec()
+
+	results := make(chan internal.Result, 1024)
+	streamed := make(chan error, 1)
+	go func() {
+		streamed <- internal.StreamUpdates(*coordinator, plan.Agent, protocol, results, opts.interval)
+	}()
+
+	time.Sleep(plan.StartIn)
+	if err := opts.run(exec, results); err != nil {
+		fail(err)
+	}
+	if err := <-streamed; err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+}
+
+// runCompare prints the change between two JSON reports, exiting with 2
+// when a metric regressed.
+func runCompare(args []string) {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	tolerance := fs.Float64("tolerance", 5, "Changes smaller than this percentage are not regressions")
+	alpha := fs.Float64("alpha", 0.05, "Significance level for the statistical tests")
+	output := fs.String("output", "default", "Output format: default, json")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: compare [flags] base.json new.json")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	base, err := internal.LoadReport(fs.Arg(0))
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	cur, err := internal.LoadReport(fs.Arg(1))
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+
+	comparisons := internal.CompareReports(base, cur, *tolerance/100, *alpha)
+	internal.PrintComparison(os.Stdout, comparisons, *output)
+	if internal.Regressed(comparisons) {
+		os.Exit(2)
+	}
 }
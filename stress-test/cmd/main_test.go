@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+
+	"stress-test/internal"
+)
+
+func TestWithoutFlags(t *testing.T) {
+	cases := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"separate values", []string{"--url", "http://x", "--agents", "3", "--listen", ":7000", "-c", "10"}, []string{"--url", "http://x", "-c", "10"}},
+		{"inline values", []string{"-agents=3", "--listen=:7000", "--rate", "50"}, []string{"--rate", "50"}},
+		{"single dash", []string{"-agents", "2", "-url", "http://x"}, []string{"-url", "http://x"}},
+		{"values are not flags", []string{"-H", "agents", "--url", "http://x"}, []string{"-H", "agents", "--url", "http://x"}},
+		{"similar names kept", []string{"--agents-file", "a", "--listener", "b"}, []string{"--agents-file", "a", "--listener", "b"}},
+		{"nothing to drop", []string{"--url", "http://x"}, []string{"--url", "http://x"}},
+		{"thresholds stay on the coordinator", []string{"--threshold", "p95<200ms", "--thresholds-file=slo.txt", "--agent-timeout", "1m", "--url", "http://x"}, []string{"--url", "http://x"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := withoutFlags(tc.args, coordinatorFlags...); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestExecutorContentType(t *testing.T) {
+	cases := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"no default", []string{"--body", "{}"}, nil},
+		{"flag", []string{"--body", "{}", "--content-type", "application/json"}, []string{"application/json"}},
+		{"from -H", []string{"-H", "Content-Type: text/csv"}, []string{"text/csv"}},
+		{"flag replaces -H", []string{"-H", "Content-Type: text/csv", "--content-type", "application/json"}, []string{"application/json"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			o, err := parseOptions("test", append([]string{"--url", "http://127.0.0.1/", "--method", "post"}, tc.args...), flag.ContinueOnError)
+			if err != nil {
+				t.Fatal(err)
+			}
+			exec, _, _, err := o.executor()
+			if err != nil {
+				t.Fatal(err)
+			}
+			spec := exec.(internal.RequestSpec)
+			if spec.Method != "POST" {
+				t.Errorf("Expected the method upper-cased, got %q", spec.Method)
+			}
+			if got := spec.Headers.Values("Content-Type"); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Expected Content-Type %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestExecutorBodyAndBodyFile(t *testing.T) {
+	o, err := parseOptions("test", []string{"--url", "http://127.0.0.1/", "--body", "{}", "--body-file", "body.json"}, flag.ContinueOnError)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := o.executor(); err == nil {
+		t.Error("Expected an error when both --body and --body-file are set")
+	}
+}
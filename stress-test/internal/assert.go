@@ -0,0 +1,130 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Assertions are extra success criteria checked against every response. A
+// response failing any of them counts as failed, with the first failing
+// assertion reported. Status ranges such as "200-299" replace the default
+// status check; JSON assertions map a path to its expected value, or to ""
+// when the path only has to exist.
+type Assertions struct {
+	Status   []string          `yaml:"status"`
+	Contains []string          `yaml:"contains"`
+	Matches  []string          `yaml:"matches"`
+	JSON     map[string]string `yaml:"json"`
+	MaxSize  int               `yaml:"max_size"`
+
+	status  [][2]int
+	matches []*regexp.Regexp
+}
+
+func (a *Assertions) Empty() bool {
+	return len(a.Status) == 0 && len(a.Contains) == 0 && len(a.Matches) == 0 && len(a.JSON) == 0 && a.MaxSize == 0
+}
+
+func (a *Assertions) compile() error {
+	a.status = a.status[:0]
+	for _, entry := range a.Status {
+		for _, part := range strings.Split(entry, ",") {
+			part = strings.TrimSpace(part)
+			from, to, isRange := strings.Cut(part, "-")
+			low, err := strconv.Atoi(from)
+			if err != nil {
+				return fmt.Errorf("invalid status %q", part)
+			}
+			high := low
+			if isRange {
+				if high, err = strconv.Atoi(to); err != nil || high < low {
+					return fmt.Errorf("invalid status range %q", part)
+				}
+			}
+			a.status = append(a.status, [2]int{low, high})
+		}
+	}
+	a.matches = a.matches[:0]
+	for _, expr := range a.Matches {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("invalid body regex: %w", err)
+		}
+		a.matches = append(a.matches, re)
+	}
+	return nil
+}
+
+// Check returns the first assertion the response fails, or "" when it passes
+// them all. statusChecked tells whether a status range decided the status.
+func (a *Assertions) Check(status int, body []byte) (failed string, statusChecked bool) {
+	if len(a.status) > 0 {
+		ok := false
+		for _, r := range a.status {
+			ok = ok || status >= r[0] && status <= r[1]
+		}
+		if !ok {
+			return "status " + strings.Join(a.Status, ","), true
+		}
+		statusChecked = true
+	}
+	if a.MaxSize > 0 && len(body) > a.MaxSize {
+		return fmt.Sprintf("max_size %d", a.MaxSize), statusChecked
+	}
+	for _, s := range a.Contains {
+		if !bytes.Contains(body, []byte(s)) {
+			return fmt.Sprintf("contains %q", s), statusChecked
+		}
+	}
+	for _, re := range a.matches {
+		if !re.Match(body) {
+			return fmt.Sprintf("matches /%s/", re), statusChecked
+		}
+	}
+	if len(a.JSON) > 0 {
+		doc, err := DecodeJSON(body)
+		for path, want := range a.JSON {
+			if err != nil {
+				return "json " + path, statusChecked
+			}
+			got, ok := LookupJSON(doc, path)
+			if !ok || want != "" && fmt.Sprint(got) != want {
+				return "json " + path, statusChecked
+			}
+		}
+	}
+	return "", statusChecked
+}
+
+// ParseJSONAssertion reads "path=value", or a bare path that must exist.
+func ParseJSONAssertion(s string) (path, value string) {
+	path, value, _ = strings.Cut(s, "=")
+	return strings.TrimSpace(path), strings.TrimSpace(value)
+}
+
+// NewAssertions compiles a, returning nil when it asserts nothing so callers
+// can skip reading bodies.
+func NewAssertions(a Assertions) (*Assertions, error) {
+	if a.Empty() {
+		return nil, nil
+	}
+	if err := a.compile(); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func writeAssertionsCSV(w *csv.Writer, failures map[string]int) {
+	if len(failures) == 0 {
+		return
+	}
+	w.Write(nil)
+	w.Write([]string{"Assertion", "Failures"})
+	for name, count := range failures {
+		w.Write([]string{name, strconv.Itoa(count)})
+	}
+}
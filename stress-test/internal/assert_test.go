@@ -0,0 +1,71 @@
+package internal
+
+import "testing"
+
+func TestAssertionsCheck(t *testing.T) {
+	body := []byte(`{"data":{"status":"PAID","id":42,"items":[{"sku":"a"}]}}`)
+	cases := []struct {
+		name          string
+		assert        Assertions
+		status        int
+		body          []byte
+		failed        string
+		statusChecked bool
+	}{
+		{"all pass", Assertions{Status: []string{"200-299"}, Contains: []string{"PAID"}, Matches: []string{`"id":\d+`}, JSON: map[string]string{"data.status": "PAID"}}, 201, body, "", true},
+		{"status list", Assertions{Status: []string{"200, 304"}}, 304, nil, "", true},
+		{"status outside ranges", Assertions{Status: []string{"200-299", "404"}}, 500, body, "status 200-299,404", true},
+		{"status not checked", Assertions{Contains: []string{"PAID"}}, 500, body, "", false},
+		{"too large", Assertions{MaxSize: 10}, 200, body, "max_size 10", false},
+		{"missing substring", Assertions{Contains: []string{"PAID", "SHIPPED"}}, 200, body, `contains "SHIPPED"`, false},
+		{"regex mismatch", Assertions{Matches: []string{`"id":"`}}, 200, body, `matches /"id":"/`, false},
+		{"json number", Assertions{JSON: map[string]string{"data.id": "42"}}, 200, body, "", false},
+		{"json path exists", Assertions{JSON: map[string]string{"$.data.items[0].sku": ""}}, 200, body, "", false},
+		{"json wrong value", Assertions{JSON: map[string]string{"data.status": "OPEN"}}, 200, body, "json data.status", false},
+		{"json missing path", Assertions{JSON: map[string]string{"data.items[1]": ""}}, 200, body, "json data.items[1]", false},
+		{"json invalid body", Assertions{JSON: map[string]string{"data": ""}}, 200, []byte("<html>"), "json data", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			a, err := NewAssertions(tc.assert)
+			if err != nil {
+				t.Fatal(err)
+			}
+			failed, statusChecked := a.Check(tc.status, tc.body)
+			if failed != tc.failed || statusChecked != tc.statusChecked {
+				t.Errorf("Expected %q %v, got %q %v", tc.failed, tc.statusChecked, failed, statusChecked)
+			}
+		})
+	}
+}
+
+func TestNewAssertions(t *testing.T) {
+	if a, err := NewAssertions(Assertions{}); a != nil || err != nil {
+		t.Errorf("Expected nil for empty assertions, got %v %v", a, err)
+	}
+	for _, invalid := range []Assertions{
+		{Status: []string{"2xx"}},
+		{Status: []string{"299-200"}},
+		{Status: []string{"200-abc"}},
+		{Matches: []string{"("}},
+	} {
+		if _, err := NewAssertions(invalid); err == nil {
+			t.Errorf("Expected an error for %+v", invalid)
+		}
+	}
+}
+
+func TestParseJSONAssertion(t *testing.T) {
+	cases := []struct{ in, path, value string }{
+		{"data.status=PAID", "data.status", "PAID"},
+		{" data.id = 42 ", "data.id", "42"},
+		{"data.token", "data.token", ""},
+		{"query=a=b", "query", "a=b"},
+	}
+	for _, tc := range cases {
+		if path, value := ParseJSONAssertion(tc.in); path != tc.path || value != tc.value {
+			t.Errorf("%q: expected %q %q, got %q %q", tc.in, tc.path, tc.value, path, value)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type ClientOptions struct {
+	Timeout      time.Duration
+	KeepAlive    bool
+	MaxIdleConns int
+	HTTP2        bool
+	Insecure     bool
+	CertFile     string
+	KeyFile      string
+	Proxy        string
+}
+
+// NewHTTPClient builds the client shared by every virtual user, so idle
+// connections are pooled across workers.
+func NewHTTPClient(opts ClientOptions) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableKeepAlives = !opts.KeepAlive
+	transport.MaxIdleConns = opts.MaxIdleConns
+	transport.MaxIdleConnsPerHost = opts.MaxIdleConns
+	transport.ForceAttemptHTTP2 = opts.HTTP2
+	if !opts.HTTP2 {
+		// A non-nil empty map disables the HTTP/2 upgrade on TLS connections.
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: opts.Insecure}
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if opts.Proxy != "" {
+		proxy, err := url.Parse(opts.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	return &http.Client{Transport: transport, Timeout: opts.Timeout}, nil
+}
+
+type ConnectionStats struct {
+	New    int `json:"New"`
+	Reused int `json:"Reused"`
+}
+
+func (c ConnectionStats) ReuseRate() float64 {
+	if total := c.New + c.Reused; total > 0 {
+		return float64(c.Reused) / float64(total)
+	}
+	return 0
+}
@@ -0,0 +1,145 @@
+package internal
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func get(t *testing.T, client *http.Client, url string) Result {
+	t.Helper()
+	return send(client, RequestSpec{Method: "GET", URL: url}, time.Now())
+}
+
+func TestNewHTTPClientKeepAlive(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	cases := []struct {
+		keepAlive bool
+		want      ConnectionStats
+	}{
+		{true, ConnectionStats{New: 1, Reused: 4}},
+		{false, ConnectionStats{New: 5}},
+	}
+	for _, tc := range cases {
+		client, err := NewHTTPClient(ClientOptions{KeepAlive: tc.keepAlive, MaxIdleConns: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := NewRecorder()
+		for i := 0; i < 5; i++ {
+			rec.Record(get(t, client, srv.URL))
+		}
+		if rec.Connections != tc.want {
+			t.Errorf("Expected connections %+v with keepalive %v, got %+v", tc.want, tc.keepAlive, rec.Connections)
+		}
+	}
+}
+
+func TestNewHTTPClientTLS(t *testing.T) {
+	var proto atomic.Int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proto.Store(int32(r.ProtoMajor))
+	}))
+	srv.EnableHTTP2 = true
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+
+	cases := []struct {
+		name      string
+		opts      ClientOptions
+		wantError ErrorCategory
+		wantProto int32
+	}{
+		{"unknown authority", ClientOptions{HTTP2: true}, ErrorTLS, 0},
+		{"insecure http2", ClientOptions{Insecure: true, HTTP2: true}, "", 2},
+		{"insecure http1", ClientOptions{Insecure: true}, "", 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			proto.Store(0)
+			client, err := NewHTTPClient(tc.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res := get(t, client, srv.URL)
+			if res.Error != tc.wantError || proto.Load() != tc.wantProto {
+				t.Errorf("Expected error %q over HTTP/%d, got %q over HTTP/%d", tc.wantError, tc.wantProto, res.Error, proto.Load())
+			}
+		})
+	}
+}
+
+func TestNewHTTPClientTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	client, err := NewHTTPClient(ClientOptions{Timeout: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := get(t, client, srv.URL); res.Error != ErrorTimeout || res.Duration < 20*time.Millisecond {
+		t.Errorf("Expected a timeout after 20ms, got %+v", res)
+	}
+}
+
+func TestNewHTTPClientProxy(t *testing.T) {
+	var proxied atomic.Value
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied.Store(r.URL.String())
+	}))
+	defer proxy.Close()
+
+	client, err := NewHTTPClient(ClientOptions{Proxy: proxy.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := get(t, client, "http://target.example.test/path"); res.StatusCode != 200 {
+		t.Fatalf("Expected the proxy to answer, got %+v", res)
+	}
+	if got := proxied.Load(); got != "http://target.example.test/path" {
+		t.Errorf("Expected the request sent through the proxy, got %v", got)
+	}
+}
+
+func TestNewHTTPClientInvalidOptions(t *testing.T) {
+	cases := []struct {
+		name string
+		opts ClientOptions
+	}{
+		{"proxy", ClientOptions{Proxy: "://proxy"}},
+		{"certificate", ClientOptions{CertFile: "missing.pem", KeyFile: "missing.key"}},
+		{"key without certificate", ClientOptions{KeyFile: "missing.key"}},
+	}
+	for _, tc := range cases {
+		if _, err := NewHTTPClient(tc.opts); err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		}
+	}
+}
+
+func TestConnectionReuseRate(t *testing.T) {
+	cases := []struct {
+		stats ConnectionStats
+		want  float64
+	}{
+		{ConnectionStats{}, 0},
+		{ConnectionStats{New: 4}, 0},
+		{ConnectionStats{New: 1, Reused: 3}, 0.75},
+	}
+	for _, tc := range cases {
+		if got := tc.stats.ReuseRate(); got != tc.want {
+			t.Errorf("Expected reuse rate %v for %+v, got %v", tc.want, tc.stats, got)
+		}
+	}
+}
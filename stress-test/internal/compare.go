@@ -0,0 +1,248 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// Comparison is one metric of two runs. Delta is the relative change, except
+// for error_rate where it is the change in percentage points. PValue is -1
+// when the reports do not carry the samples needed for a significance test;
+// such a metric is never a regression, only Untested when it got worse by
+// more than the tolerance.
+type Comparison struct {
+	Metric     string  `json:"Metric"`
+	Base       float64 `json:"Base"`
+	New        float64 `json:"New"`
+	Delta      float64 `json:"Delta"`
+	PValue     float64 `json:"PValue"`
+	Regression bool    `json:"Regression"`
+	Untested   bool    `json:"Untested"`
+
+	kind metricKind
+}
+
+// LoadReport reads a report written with --output json.
+func LoadReport(path string) (Report, error) {
+	var r Report
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return r, fmt.Errorf("parse report %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// compared lists the metrics compare reports, in order. Those kept per
+// interval in the time series have samples for a significance test.
+var compared = []struct {
+	name           string
+	higherIsBetter bool
+	samples        func(IntervalStats) float64
+}{
+	{"rps", true, func(s IntervalStats) float64 { return s.RPS }},
+	{"mean", false, nil},
+	{"p50", false, func(s IntervalStats) float64 { return float64(s.P50) }},
+	{"p75", false, nil},
+	{"p90", false, func(s IntervalStats) float64 { return float64(s.P90) }},
+	{"p95", false, func(s IntervalStats) float64 { return float64(s.P95) }},
+	{"p99", false, func(s IntervalStats) float64 { return float64(s.P99) }},
+	{"p999", false, nil},
+	{"max", false, nil},
+}
+
+// CompareReports computes the change of every metric from base to cur. A
+// metric regresses when it got worse by more than tolerance (a fraction,
+// 0.05 for 5%) and the change is significant at alpha. Throughput and the
+// percentiles kept per interval are tested with Welch's t-test over the time
+// series, the error rate with a two-proportion z-test. Metrics without
+// samples are shown for reference but never fail the comparison.
+func CompareReports(base, cur Report, tolerance, alpha float64) []Comparison {
+	var out []Comparison
+	for _, c := range compared {
+		m := metrics[c.name]
+		cmp := Comparison{Metric: c.name, Base: m.value(base), New: m.value(cur), PValue: -1, kind: m.kind}
+		if cmp.Base != 0 {
+			cmp.Delta = (cmp.New - cmp.Base) / cmp.Base
+		}
+		if c.samples != nil {
+			cmp.PValue = welch(series(base.TimeSeries, c.samples), series(cur.TimeSeries, c.samples))
+		}
+		worse := cmp.New > cmp.Base
+		if c.higherIsBetter {
+			worse = cmp.New < cmp.Base
+		}
+		cmp.judge(worse && math.Abs(cmp.Delta) > tolerance, alpha)
+		out = append(out, cmp)
+	}
+
+	errRate := Comparison{
+		Metric: "error_rate",
+		Base:   base.ErrorRate,
+		New:    cur.ErrorRate,
+		Delta:  (cur.ErrorRate - base.ErrorRate) * 100,
+		PValue: proportions(base.FailedCount, base.TotalRequests, cur.FailedCount, cur.TotalRequests),
+		kind:   kindRate,
+	}
+	// Relative to the base rate, as for the other metrics; any errors on a
+	// clean base exceed it.
+	beyond := errRate.New > errRate.Base && (errRate.Base == 0 || (errRate.New-errRate.Base)/errRate.Base > tolerance)
+	errRate.judge(beyond, alpha)
+	return append(out, errRate)
+}
+
+func (c *Comparison) judge(beyondTolerance bool, alpha float64) {
+	c.Untested = beyondTolerance && c.PValue < 0
+	c.Regression = beyondTolerance && c.PValue >= 0 && c.PValue < alpha
+}
+
+// Regressed reports whether any comparison is a significant regression,
+// which fails the compare command.
+func Regressed(comparisons []Comparison) bool {
+	for _, c := range comparisons {
+		if c.Regression {
+			return true
+		}
+	}
+	return false
+}
+
+func series(ts []IntervalStats, value func(IntervalStats) float64) []float64 {
+	var out []float64
+	for _, s := range ts {
+		if s.Requests > 0 {
+			out = append(out, value(s))
+		}
+	}
+	return out
+}
+
+// welch returns the two-sided p-value of Welch's t-test, or -1 with fewer
+// than two samples on either side.
+func welch(a, b []float64) float64 {
+	if len(a) < 2 || len(b) < 2 {
+		return -1
+	}
+	ma, va := meanVar(a)
+	mb, vb := meanVar(b)
+	na, nb := float64(len(a)), float64(len(b))
+	se2 := va/na + vb/nb
+	if se2 == 0 {
+		if ma == mb {
+			return 1
+		}
+		return 0
+	}
+	t := (mb - ma) / math.Sqrt(se2)
+	df := se2 * se2 / (va*va/(na*na*(na-1)) + vb*vb/(nb*nb*(nb-1)))
+	return incompleteBeta(df/2, 0.5, df/(df+t*t))
+}
+
+func meanVar(xs []float64) (mean, variance float64) {
+	for _, x := range xs {
+		mean += x
+	}
+	mean /= float64(len(xs))
+	for _, x := range xs {
+		variance += (x - mean) * (x - mean)
+	}
+	return mean, variance / float64(len(xs)-1)
+}
+
+// proportions returns the two-sided p-value of a two-proportion z-test.
+func proportions(f1, n1, f2, n2 int) float64 {
+	if n1 == 0 || n2 == 0 {
+		return -1
+	}
+	p1, p2 := float64(f1)/float64(n1), float64(f2)/float64(n2)
+	p := float64(f1+f2) / float64(n1+n2)
+	se := math.Sqrt(p * (1 - p) * (1/float64(n1) + 1/float64(n2)))
+	if se == 0 {
+		return 1
+	}
+	return math.Erfc(math.Abs(p2-p1) / se / math.Sqrt2)
+}
+
+// incompleteBeta is the regularized incomplete beta function I_x(a, b),
+// evaluated with its continued fraction.
+func incompleteBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	front := math.Exp(lab - la - lb + a*math.Log(x) + b*math.Log(1-x))
+	if x > (a+1)/(a+b+2) {
+		return 1 - front*betaFraction(b, a, 1-x)/b
+	}
+	return front * betaFraction(a, b, x) / a
+}
+
+func betaFraction(a, b, x float64) float64 {
+	const tiny = 1e-300
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1.0; m <= 200; m++ {
+		for _, num := range []float64{
+			m * (b - m) * x / ((a + 2*m - 1) * (a + 2*m)),
+			-(a + m) * (a + b + m) * x / ((a + 2*m) * (a + 2*m + 1)),
+		} {
+			d = 1 + num*d
+			if math.Abs(d) < tiny {
+				d = tiny
+			}
+			c = 1 + num/c
+			if math.Abs(c) < tiny {
+				c = tiny
+			}
+			d = 1 / d
+			h *= d * c
+		}
+		if math.Abs(d*c-1) < 1e-12 {
+			break
+		}
+	}
+	return h
+}
+
+func PrintComparison(w io.Writer, comparisons []Comparison, output string) {
+	if output == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(comparisons)
+		return
+	}
+
+	fmt.Fprintf(w, "%-12s %14s %14s %10s %8s\n", "Metric", "Base", "New", "Delta", "p-value")
+	for _, c := range comparisons {
+		base, cur := formatMetric(c.kind, c.Base), formatMetric(c.kind, c.New)
+		delta := fmt.Sprintf("%+.2f%%", c.Delta*100)
+		if c.Metric == "error_rate" {
+			delta = fmt.Sprintf("%+.2fpp", c.Delta)
+		}
+		p := "-"
+		if c.PValue >= 0 {
+			p = fmt.Sprintf("%.4f", c.PValue)
+		}
+		flag := ""
+		if c.Regression {
+			flag = "  REGRESSION"
+		} else if c.Untested {
+			flag = "  untested"
+		}
+		fmt.Fprintf(w, "%-12s %14s %14s %10s %8s%s\n", c.Metric, base, cur, delta, p, flag)
+	}
+}
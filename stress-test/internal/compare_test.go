@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestWelch(t *testing.T) {
+	cases := []struct {
+		name string
+		a, b []float64
+		want float64
+	}{
+		{"identical", []float64{1, 2, 3, 4, 5}, []float64{1, 2, 3, 4, 5}, 1},
+		// t = 1 with 8 degrees of freedom.
+		{"shifted", []float64{1, 2, 3, 4, 5}, []float64{2, 3, 4, 5, 6}, 0.346594},
+		{"constant equal", []float64{3, 3, 3}, []float64{3, 3}, 1},
+		{"constant different", []float64{3, 3, 3}, []float64{4, 4}, 0},
+		{"too few samples", []float64{1}, []float64{1, 2}, -1},
+	}
+
+	for _, tc := range cases {
+		if got := welch(tc.a, tc.b); math.Abs(got-tc.want) > 1e-5 {
+			t.Errorf("%s: expected p=%v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestProportions(t *testing.T) {
+	cases := []struct {
+		name           string
+		f1, n1, f2, n2 int
+		want           float64
+	}{
+		{"no failures", 0, 100, 0, 100, 1},
+		{"same rate", 5, 100, 10, 200, 1},
+		// z = 0.02 / sqrt(0.02*0.98*2/1000) = 3.1944.
+		{"higher rate", 10, 1000, 30, 1000, 0.001401},
+		{"empty run", 0, 0, 1, 10, -1},
+	}
+
+	for _, tc := range cases {
+		if got := proportions(tc.f1, tc.n1, tc.f2, tc.n2); math.Abs(got-tc.want) > 1e-5 {
+			t.Errorf("%s: expected p=%v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
+
+// run builds a report whose time series has one interval per latency, so
+// p50 through p99 and rps carry samples for the significance tests.
+func run(mean time.Duration, failed, total int, latencies ...time.Duration) Report {
+	r := Report{TotalRequests: total, FailedCount: failed, Mean: mean}
+	if total > 0 {
+		r.ErrorRate = float64(failed) / float64(total)
+	}
+	for _, l := range latencies {
+		r.TimeSeries = append(r.TimeSeries, IntervalStats{Requests: 100, RPS: 100, P50: l, P90: l, P95: l, P99: l})
+		r.P50, r.P90, r.P95, r.P99, r.RPS = l, l, l, l, 100
+	}
+	return r
+}
+
+func TestCompareReportsDecision(t *testing.T) {
+	ms := time.Millisecond
+	steady := []time.Duration{10 * ms, 11 * ms, 10 * ms, 9 * ms, 10 * ms, 11 * ms}
+	slower := []time.Duration{20 * ms, 21 * ms, 20 * ms, 19 * ms, 20 * ms, 21 * ms}
+
+	cases := []struct {
+		name      string
+		base, cur Report
+		regressed bool
+		untested  []string
+	}{
+		{"identical runs", run(10*ms, 1, 1000, steady...), run(10*ms, 1, 1000, steady...), false, nil},
+		{"slower percentiles", run(10*ms, 0, 1000, steady...), run(10*ms, 0, 1000, slower...), true, nil},
+		{"mean without samples", run(10*ms, 0, 1000, steady...), run(30*ms, 0, 1000, steady...), false, []string{"mean"}},
+		{"error rate within tolerance", run(10*ms, 10000, 100000, steady...), run(10*ms, 10300, 100000, steady...), false, nil},
+		{"error rate beyond tolerance", run(10*ms, 100, 10000, steady...), run(10*ms, 200, 10000, steady...), true, nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			comparisons := CompareReports(tc.base, tc.cur, 0.05, 0.05)
+			if got := Regressed(comparisons); got != tc.regressed {
+				t.Errorf("Expected regressed=%v, got %+v", tc.regressed, comparisons)
+			}
+			var untested []string
+			for _, c := range comparisons {
+				if c.Untested {
+					untested = append(untested, c.Metric)
+				}
+				if c.Untested && c.Regression {
+					t.Errorf("Expected untested %s not to be a regression", c.Metric)
+				}
+			}
+			if len(untested) != len(tc.untested) || (len(untested) > 0 && untested[0] != tc.untested[0]) {
+				t.Errorf("Expected untested %v, got %v", tc.untested, untested)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Plan is what the coordinator hands to each agent once every expected agent
+// has joined. Args are the coordinator's own flags, so all agents run the
+// same test; each one takes its share of requests, concurrency and rate.
+type Plan struct {
+	Agent   int           `json:"Agent"`
+	Agents  int           `json:"Agents"`
+	Args    []string      `json:"Args"`
+	StartIn time.Duration `json:"StartIn"`
+}
+
+// Share splits total across agents, giving the remainder to the first ones.
+func (p Plan) Share(total int) int {
+	share := total / p.Agents
+	if p.Agent < total%p.Agents {
+		share++
+	}
+	return share
+}
+
+func (p Plan) ShareRate(rate float64) float64 {
+	return rate / float64(p.Agents)
+}
+
+// AgentUpdate carries what an agent recorded since its previous update.
+type AgentUpdate struct {
+	Agent    int       `json:"Agent"`
+	Recorder *Recorder `json:"Recorder,omitempty"`
+	Final    bool      `json:"Final,omitempty"`
+	Error    string    `json:"Error,omitempty"`
+}
+
+// startDelay gives every agent time to receive its plan, so they start
+// together regardless of their clocks.
+const startDelay = time.Second
+
+// DefaultAgentTimeout is how long an agent may go without sending an update
+// before the coordinator stops waiting for its final one.
+const DefaultAgentTimeout = 30 * time.Second
+
+// Coordinator waits for Agents agents, sends them the plan and merges their
+// updates into a single recorder. Agents send an update every interval, so
+// one that stays silent for Timeout is given up on.
+type Coordinator struct {
+	Agents   int
+	Args     []string
+	Interval time.Duration
+	Timeout  time.Duration
+
+	mu      sync.Mutex
+	joined  int
+	taken   []bool
+	ready   chan struct{}
+	startAt time.Time
+	updates chan AgentUpdate
+}
+
+func NewCoordinator(agents int, args []string, interval time.Duration) *Coordinator {
+	return &Coordinator{
+		Agents:   agents,
+		Args:     args,
+		Interval: interval,
+		Timeout:  DefaultAgentTimeout,
+		taken:    make([]bool, agents),
+		ready:    make(chan struct{}),
+		updates:  make(chan AgentUpdate),
+	}
+}
+
+func (c *Coordinator) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /join", c.join)
+	mux.HandleFunc("POST /update", c.update)
+	return mux
+}
+
+func (c *Coordinator) join(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	if c.joined == c.Agents {
+		c.mu.Unlock()
+		http.Error(w, "all agents already joined", http.StatusConflict)
+		return
+	}
+	agent := slices.Index(c.taken, false)
+	c.taken[agent] = true
+	plan := Plan{Agent: agent, Agents: c.Agents, Args: c.Args, StartIn: startDelay}
+	c.joined++
+	if c.joined == c.Agents {
+		c.startAt = time.Now().Add(startDelay)
+		close(c.ready)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-c.ready:
+	case <-r.Context().Done():
+		// An agent that leaves before everyone joined frees its slot for
+		// another one; once the plan is out it is waited for like the rest.
+		c.mu.Lock()
+		if c.joined < c.Agents {
+			c.taken[agent] = false
+			c.joined--
+		}
+		c.mu.Unlock()
+		return
+	}
+	c.mu.Lock()
+	plan.StartIn = time.Until(c.startAt)
+	c.mu.Unlock()
+	writeJSON(w, plan)
+}
+
+func (c *Coordinator) update(w http.ResponseWriter, r *http.Request) {
+	var u AgentUpdate
+	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if u.Agent < 0 || u.Agent >= c.Agents {
+		http.Error(w, "unknown agent", http.StatusBadRequest)
+		return
+	}
+	select {
+	case c.updates <- u:
+		w.WriteHeader(http.StatusNoContent)
+	case <-r.Context().Done():
+	}
+}
+
+// Run serves agents on ln until all of them sent their final update or timed
+// out, merging every update into rec and passing a snapshot to the sinks
+// each interval. It returns how long the test ran.
+func (c *Coordinator) Run(ctx context.Context, ln net.Listener, rec *Recorder, sinks ...func(Snapshot)) (time.Duration, error) {
+	srv := &http.Server{Handler: c.Handler()}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	select {
+	case <-c.ready:
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	c.mu.Lock()
+	start := c.startAt
+	c.mu.Unlock()
+	rec.start, rec.last = start, start
+	time.Sleep(time.Until(start))
+
+	emit := func(s Snapshot) {
+		for _, sink := range sinks {
+			sink(s)
+		}
+	}
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+
+	var errs []error
+	done := 0
+	finished := make([]bool, c.Agents)
+	lastSeen := make([]time.Time, c.Agents)
+	for i := range lastSeen {
+		lastSeen[i] = start
+	}
+	for done < c.Agents {
+		select {
+		case u := <-c.updates:
+			if u.Recorder != nil {
+				rec.Merge(u.Recorder)
+			}
+			lastSeen[u.Agent] = time.Now()
+			if u.Error != "" {
+				errs = append(errs, fmt.Errorf("agent %d: %s", u.Agent+1, u.Error))
+			}
+			if u.Final && !finished[u.Agent] {
+				finished[u.Agent] = true
+				done++
+			}
+		case now := <-ticker.C:
+			emit(rec.Snapshot(now))
+			for i, seen := range lastSeen {
+				if !finished[i] && now.Sub(seen) > c.Timeout {
+					errs = append(errs, fmt.Errorf("agent %d: no update for %v", i+1, c.Timeout))
+					finished[i] = true
+					done++
+				}
+			}
+		case <-ctx.Done():
+			return time.Since(start), ctx.Err()
+		}
+	}
+	s := rec.Snapshot(time.Now())
+	s.Final = true
+	emit(s)
+	return time.Since(start), errors.Join(errs...)
+}
+
+// Join registers with the coordinator and blocks until the test is planned.
+func Join(ctx context.Context, coordinator string) (Plan, error) {
+	var plan Plan
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, coordinator+"/join", nil)
+	if err != nil {
+		return plan, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return plan, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return plan, fmt.Errorf("join %s: %s", coordinator, resp.Status)
+	}
+	err = json.NewDecoder(resp.Body).Decode(&plan)
+	return plan, err
+}
+
+// SendUpdate posts one update to the coordinator.
+func SendUpdate(coordinator string, u AgentUpdate) error {
+	body, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	resp, err := http.Post(coordinator+"/update", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("update %s: %s", coordinator, resp.Status)
+	}
+	return nil
+}
+
+// StreamUpdates records results like Collect, but sends what was recorded
+// to the coordinator every interval instead of keeping it. A failed update
+// is kept and retried with the next one, so no results are lost.
+func StreamUpdates(coordinator string, agent int, protocol Protocol, results <-chan Result, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	newRecorder := func() *Recorder {
+		rec := NewRecorder()
+		rec.Protocol = protocol
+		return rec
+	}
+	rec := newRecorder()
+	var err error
+	flush := func(final bool) {
+		err = SendUpdate(coordinator, AgentUpdate{Agent: agent, Recorder: rec, Final: final})
+		if err == nil {
+			rec = newRecorder()
+		}
+	}
+	for {
+		select {
+		case res, ok := <-results:
+			if !ok {
+				flush(true)
+				return err
+			}
+			rec.Record(res)
+		case <-ticker.C:
+			flush(false)
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,179 @@
+package internal
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// startCoordinator runs c on a local port and returns its URL and a channel
+// with what Run returned.
+func startCoordinator(t *testing.T, c *Coordinator, rec *Recorder, sinks ...func(Snapshot)) (string, <-chan error) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+	ran := make(chan error, 1)
+	go func() {
+		_, err := c.Run(ctx, ln, rec, sinks...)
+		ran <- err
+	}()
+	return "http://" + ln.Addr().String(), ran
+}
+
+func TestCoordinatorWithAgents(t *testing.T) {
+	c := NewCoordinator(2, []string{"--url", "http://target"}, 10*time.Millisecond)
+	rec := NewRecorder()
+	var mu sync.Mutex
+	var snapshots []Snapshot
+	url, ran := startCoordinator(t, c, rec, func(s Snapshot) {
+		mu.Lock()
+		snapshots = append(snapshots, s)
+		mu.Unlock()
+	})
+
+	// Each agent sends some results, waits for an interval update to go out,
+	// then sends the rest with its final update.
+	agentResults := [][]Result{
+		{{StatusCode: 200, Duration: time.Millisecond}, {StatusCode: 200, Duration: time.Millisecond}, {StatusCode: 200, Duration: time.Millisecond}},
+		{{StatusCode: 200, Duration: time.Millisecond}, {StatusCode: 500, Duration: time.Millisecond}, {StatusCode: 200, Duration: time.Millisecond}},
+	}
+	plans := make([]Plan, len(agentResults))
+	var wg sync.WaitGroup
+	for i := range agentResults {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			plan, err := Join(context.Background(), url)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			plans[i] = plan
+
+			results := make(chan Result)
+			streamed := make(chan error, 1)
+			go func() { streamed <- StreamUpdates(url, plan.Agent, ProtocolHTTP, results, 10*time.Millisecond) }()
+			time.Sleep(plan.StartIn)
+			for j, res := range agentResults[i] {
+				if j == 2 {
+					time.Sleep(30 * time.Millisecond)
+				}
+				results <- res
+			}
+			close(results)
+			if err := <-streamed; err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	select {
+	case err := <-ran:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Run to return once both agents sent their final update")
+	}
+
+	if plans[0].Agent == plans[1].Agent || plans[0].Agents != 2 || plans[0].Args[1] != "http://target" || plans[0].StartIn <= 0 {
+		t.Errorf("Expected each agent its own slot in the same plan, got %+v", plans)
+	}
+	if _, err := Join(context.Background(), url); err == nil {
+		t.Error("Expected a third agent to be turned away")
+	}
+
+	report := rec.Report(time.Second, false)
+	if report.TotalRequests != 6 || report.FailedCount != 1 || report.StatusCodes[200] != 5 {
+		t.Errorf("Expected the results of both agents merged, got %+v", report)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(snapshots) < 2 {
+		t.Fatalf("Expected interval snapshots before the final one, got %+v", snapshots)
+	}
+	if last := snapshots[len(snapshots)-1]; !last.Final || last.Requests != 6 {
+		t.Errorf("Expected a final snapshot with every request, got %+v", last)
+	}
+}
+
+func TestCoordinatorReleasesCancelledJoin(t *testing.T) {
+	c := NewCoordinator(2, nil, time.Second)
+	srv := httptest.NewServer(c.Handler())
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := Join(ctx, srv.URL); err == nil {
+		t.Fatal("Expected the join to be cancelled")
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		c.mu.Lock()
+		joined := c.joined
+		c.mu.Unlock()
+		if joined == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the cancelled join to give its slot back")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	plans := make(chan Plan, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			plan, err := Join(context.Background(), srv.URL)
+			if err != nil {
+				t.Error(err)
+			}
+			plans <- plan
+		}()
+	}
+	a, b := <-plans, <-plans
+	if a.Agent+b.Agent != 1 {
+		t.Errorf("Expected the two agents to get slots 0 and 1, got %d and %d", a.Agent, b.Agent)
+	}
+}
+
+func TestCoordinatorGivesUpOnSilentAgent(t *testing.T) {
+	c := NewCoordinator(2, nil, 10*time.Millisecond)
+	c.Timeout = 50 * time.Millisecond
+	url, ran := startCoordinator(t, c, NewRecorder())
+
+	// Both agents join, but only one of them ever reports back.
+	plans := make(chan Plan, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			plan, err := Join(context.Background(), url)
+			if err != nil {
+				t.Error(err)
+			}
+			plans <- plan
+		}()
+	}
+	<-plans
+	silent := <-plans
+	if err := SendUpdate(url, AgentUpdate{Agent: 1 - silent.Agent, Recorder: NewRecorder(), Final: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-ran:
+		if err == nil || !strings.Contains(err.Error(), "no update") {
+			t.Errorf("Expected the silent agent reported, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Run to stop waiting for the silent agent")
+	}
+}
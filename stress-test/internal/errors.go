@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"unicode"
+
+	"google.golang.org/grpc/status"
+)
+
+type ErrorCategory string
+
+const (
+	ErrorDNS               ErrorCategory = "dns"
+	ErrorConnectionRefused ErrorCategory = "connection_refused"
+	ErrorConnectionReset   ErrorCategory = "connection_reset"
+	ErrorTimeout           ErrorCategory = "timeout"
+	ErrorTLS               ErrorCategory = "tls"
+	ErrorInvalidRequest    ErrorCategory = "invalid_request"
+	ErrorOther             ErrorCategory = "other"
+)
+
+// ClassifyError maps a transport error returned by the HTTP client to the
+// category reported for it.
+func ClassifyError(err error) ErrorCategory {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var certErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+
+	switch {
+	case errors.As(err, &dnsErr):
+		return ErrorDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorConnectionRefused
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorTimeout
+	case errors.As(err, &certErr), errors.As(err, &recordErr), errors.As(err, &alertErr),
+		errors.As(err, &authorityErr), errors.As(err, &hostnameErr), errors.As(err, &invalidErr),
+		strings.Contains(err.Error(), "tls: "), strings.Contains(err.Error(), "HTTP response to HTTPS client"):
+		return ErrorTLS
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE),
+		errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrorConnectionReset
+	default:
+		return ErrorOther
+	}
+}
+
+// ClassifyGRPCError reports a failed gRPC call under its status code, e.g.
+// unavailable or deadline_exceeded.
+func ClassifyGRPCError(err error) ErrorCategory {
+	var sb strings.Builder
+	for i, r := range status.Code(err).String() {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				sb.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return ErrorCategory(sb.String())
+}
+
+// errorResult reports a request that failed before getting a response.
+func errorResult(category ErrorCategory, err error) Result {
+	return Result{StatusCode: -1, Error: category, ErrorMessage: err.Error()}
+}
+
+// unsentResult reports a request that could not be built and was never sent.
+func unsentResult(err error) Result {
+	res := errorResult(ErrorInvalidRequest, err)
+	res.Unsent = true
+	return res
+}
+
+type ErrorSummary struct {
+	Count  int    `json:"Count"`
+	Sample string `json:"Sample"`
+}
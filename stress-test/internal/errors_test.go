@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// clientError wraps err the way net/http returns transport failures.
+func clientError(err error) error {
+	return &url.Error{Op: "Get", URL: "http://example.test", Err: &net.OpError{Op: "dial", Net: "tcp", Err: err}}
+}
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want ErrorCategory
+	}{
+		{"dns", clientError(&net.DNSError{Err: "no such host", Name: "example.test", IsNotFound: true}), ErrorDNS},
+		{"refused", clientError(os.NewSyscallError("connect", syscall.ECONNREFUSED)), ErrorConnectionRefused},
+		{"client timeout", clientError(context.DeadlineExceeded), ErrorTimeout},
+		{"read deadline", clientError(os.ErrDeadlineExceeded), ErrorTimeout},
+		{"unknown authority", clientError(x509.UnknownAuthorityError{}), ErrorTLS},
+		{"plain http server", clientError(tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}), ErrorTLS},
+		{"tls message", fmt.Errorf("remote error: tls: handshake failure"), ErrorTLS},
+		{"reset", clientError(os.NewSyscallError("read", syscall.ECONNRESET)), ErrorConnectionReset},
+		{"broken pipe", clientError(syscall.EPIPE), ErrorConnectionReset},
+		{"eof", clientError(io.EOF), ErrorConnectionReset},
+		{"unexpected eof", fmt.Errorf("read body: %w", io.ErrUnexpectedEOF), ErrorConnectionReset},
+		{"other", errors.New("something else"), ErrorOther},
+	}
+
+	for _, tc := range cases {
+		if got := ClassifyError(tc.err); got != tc.want {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestClassifyGRPCError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want ErrorCategory
+	}{
+		{status.Error(codes.Unavailable, "connection refused"), "unavailable"},
+		{status.Error(codes.DeadlineExceeded, "deadline"), "deadline_exceeded"},
+		{status.Error(codes.ResourceExhausted, "quota"), "resource_exhausted"},
+		{status.Error(codes.InvalidArgument, "bad"), "invalid_argument"},
+		{errors.New("not a status"), "unknown"},
+	}
+
+	for _, tc := range cases {
+		if got := ClassifyGRPCError(tc.err); got != tc.want {
+			t.Errorf("Expected %q for %v, got %q", tc.want, tc.err, got)
+		}
+	}
+}
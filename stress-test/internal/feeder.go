@@ -0,0 +1,150 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+type FeedMode string
+
+const (
+	// FeedSequential hands out every row once, then stops the workers.
+	FeedSequential FeedMode = "sequential"
+	FeedCircular   FeedMode = "circular"
+	FeedRandom     FeedMode = "random"
+)
+
+// Feeder supplies one data row per iteration, whose fields become template
+// values. With Workers set, rows are partitioned so worker n only uses rows
+// n, n+Workers, n+2*Workers..., which keeps unique data such as tokens from
+// being used by two workers at once.
+type Feeder struct {
+	Rows    []map[string]any
+	Mode    FeedMode
+	Workers int
+
+	next atomic.Int64
+}
+
+// LoadFeeder reads a CSV file with a header row, or a JSONL file with one
+// object per line, chosen by the file extension.
+func LoadFeeder(path string, mode FeedMode) (*Feeder, error) {
+	switch mode {
+	case FeedSequential, FeedCircular, FeedRandom:
+	default:
+		return nil, fmt.Errorf("unknown feeder mode %q (sequential, circular or random)", mode)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rows []map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		rows, err = readCSVRows(csv.NewReader(f))
+	case ".jsonl", ".ndjson":
+		rows, err = readJSONLRows(bufio.NewScanner(f))
+	default:
+		return nil, fmt.Errorf("feeder %s: expected a .csv or .jsonl file", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("feeder %s: %w", path, err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("feeder %s has no rows", path)
+	}
+	return &Feeder{Rows: rows, Mode: mode}, nil
+}
+
+func readCSVRows(r *csv.Reader) ([]map[string]any, error) {
+	header, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]map[string]any, 0, len(records))
+	for _, record := range records {
+		row := make(map[string]any, len(header))
+		for i, name := range header {
+			if i < len(record) {
+				row[name] = record[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func readJSONLRows(sc *bufio.Scanner) ([]map[string]any, error) {
+	sc.Buffer(nil, 1<<20)
+	var rows []map[string]any
+	for line := 1; sc.Scan(); line++ {
+		text := bytes.TrimSpace(sc.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		v, err := DecodeJSON(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		row, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected a JSON object", line)
+		}
+		rows = append(rows, row)
+	}
+	return rows, sc.Err()
+}
+
+// Slice keeps the part-th of parts interleaved shares of the rows, so agents
+// of a distributed run do not reuse each other's data.
+func (f *Feeder) Slice(part, parts int) {
+	var kept []map[string]any
+	for i := part; i < len(f.Rows); i += parts {
+		kept = append(kept, f.Rows[i])
+	}
+	f.Rows = kept
+}
+
+// Next returns the row for the virtual user's next iteration, or false once
+// its rows are used up.
+func (f *Feeder) Next(vu *VirtualUser) (map[string]any, bool) {
+	offset, stride := 0, 1
+	if f.Workers > 1 {
+		offset, stride = vu.ID%f.Workers, f.Workers
+	}
+	n := (len(f.Rows) - offset + stride - 1) / stride
+	if n <= 0 {
+		return nil, false
+	}
+
+	var i int
+	switch {
+	case f.Mode == FeedRandom:
+		i = rand.IntN(n)
+	case stride > 1:
+		i = vu.fed
+		vu.fed++
+	default:
+		i = int(f.next.Add(1) - 1)
+	}
+	if f.Mode == FeedCircular {
+		i %= n
+	}
+	if i >= n {
+		return nil, false
+	}
+	return f.Rows[offset+i*stride], true
+}
@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeFeeder(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFeeder(t *testing.T) {
+	cases := []struct {
+		name    string
+		file    string
+		content string
+		mode    FeedMode
+		want    []map[string]any
+		err     string
+	}{
+		{"csv", "users.csv", "user,cep\nana,01001000\nbia,\n", FeedCircular,
+			[]map[string]any{{"user": "ana", "cep": "01001000"}, {"user": "bia", "cep": ""}}, ""},
+		{"jsonl", "users.jsonl", "{\"user\":\"ana\",\"id\":7}\n\n  {\"user\":\"bia\"}\n", FeedSequential,
+			[]map[string]any{{"user": "ana", "id": "7"}, {"user": "bia"}}, ""},
+		{"ndjson", "users.NDJSON", "{\"user\":\"ana\"}\n", FeedRandom, []map[string]any{{"user": "ana"}}, ""},
+		{"csv header only", "users.csv", "user,cep\n", FeedCircular, nil, "has no rows"},
+		{"jsonl not an object", "users.jsonl", "{\"user\":\"ana\"}\n[1,2]\n", FeedCircular, nil, "line 2: expected a JSON object"},
+		{"jsonl invalid", "users.jsonl", "{\"user\":\n", FeedCircular, nil, "line 1"},
+		{"csv ragged", "users.csv", "user,cep\nana,1,extra\n", FeedCircular, nil, "wrong number of fields"},
+		{"unknown extension", "users.txt", "user\nana\n", FeedCircular, nil, "expected a .csv or .jsonl file"},
+		{"unknown mode", "users.csv", "user\nana\n", "shuffle", nil, "unknown feeder mode"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := LoadFeeder(writeFeeder(t, tc.file, tc.content), tc.mode)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("Expected error containing %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Normalise json.Number so rows compare as strings.
+			for _, row := range f.Rows {
+				for k, v := range row {
+					if n, ok := v.(interface{ String() string }); ok {
+						row[k] = n.String()
+					}
+				}
+			}
+			if !reflect.DeepEqual(f.Rows, tc.want) || f.Mode != tc.mode {
+				t.Errorf("Expected %v, got %v (%s)", tc.want, f.Rows, f.Mode)
+			}
+		})
+	}
+}
+
+func rows(n int) []map[string]any {
+	out := make([]map[string]any, n)
+	for i := range out {
+		out[i] = map[string]any{"n": i}
+	}
+	return out
+}
+
+// take draws up to count rows for vu and returns their numbers.
+func take(f *Feeder, vu *VirtualUser, count int) []int {
+	var got []int
+	for i := 0; i < count; i++ {
+		row, ok := f.Next(vu)
+		if !ok {
+			break
+		}
+		got = append(got, row["n"].(int))
+	}
+	return got
+}
+
+func TestFeederNext(t *testing.T) {
+	cases := []struct {
+		name   string
+		feeder *Feeder
+		vu     int
+		draws  int
+		want   []int
+	}{
+		{"sequential stops", &Feeder{Rows: rows(3), Mode: FeedSequential}, 0, 5, []int{0, 1, 2}},
+		{"circular wraps", &Feeder{Rows: rows(3), Mode: FeedCircular}, 0, 5, []int{0, 1, 2, 0, 1}},
+		{"partitioned sequential", &Feeder{Rows: rows(7), Mode: FeedSequential, Workers: 3}, 1, 5, []int{1, 4}},
+		{"partitioned circular", &Feeder{Rows: rows(7), Mode: FeedCircular, Workers: 3}, 2, 4, []int{2, 5, 2, 5}},
+		{"partition without rows", &Feeder{Rows: rows(2), Mode: FeedCircular, Workers: 3}, 2, 3, nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := take(tc.feeder, NewVirtualUser(tc.vu, nil), tc.draws); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Expected rows %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestFeederPartitionsAreDisjoint(t *testing.T) {
+	f := &Feeder{Rows: rows(10), Mode: FeedSequential, Workers: 3}
+	seen := make(map[int]int)
+	for id := 0; id < 3; id++ {
+		for _, n := range take(f, NewVirtualUser(id, nil), 10) {
+			if owner, ok := seen[n]; ok {
+				t.Errorf("Expected row %d used once, got workers %d and %d", n, owner, id)
+			}
+			seen[n] = id
+		}
+	}
+	if len(seen) != 10 {
+		t.Errorf("Expected every row used, got %v", seen)
+	}
+}
+
+func TestFeederRandomStaysInPartition(t *testing.T) {
+	f := &Feeder{Rows: rows(9), Mode: FeedRandom, Workers: 3}
+	vu := NewVirtualUser(4, nil)
+	for _, n := range take(f, vu, 50) {
+		if n%3 != 1 {
+			t.Fatalf("Expected only rows of partition 1, got %d", n)
+		}
+	}
+}
+
+func TestFeederSlice(t *testing.T) {
+	f := &Feeder{Rows: rows(7)}
+	f.Slice(1, 3)
+	if got := take(&Feeder{Rows: f.Rows, Mode: FeedSequential}, NewVirtualUser(0, nil), 10); !reflect.DeepEqual(got, []int{1, 4}) {
+		t.Errorf("Expected agent 1 of 3 to keep rows 1 and 4, got %v", got)
+	}
+}
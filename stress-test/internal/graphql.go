@@ -0,0 +1,121 @@
+package internal
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// GraphQLSpec posts a query document and counts responses carrying a
+// non-empty errors array as failures, even when the HTTP status is 200.
+type GraphQLSpec struct {
+	request RequestSpec
+}
+
+func NewGraphQLSpec(url, query, operation string, variables []byte, headers http.Header, assert *Assertions) (*GraphQLSpec, error) {
+	doc := map[string]any{"query": query}
+	if operation != "" {
+		doc["operationName"] = operation
+	}
+	if len(variables) > 0 {
+		var vars map[string]any
+		if err := json.Unmarshal(variables, &vars); err != nil {
+			return nil, fmt.Errorf("graphql variables: %w", err)
+		}
+		doc["variables"] = vars
+	}
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	headers = headers.Clone()
+	if headers.Get("Content-Type") == "" {
+		headers.Set("Content-Type", "application/json")
+	}
+	return &GraphQLSpec{request: RequestSpec{Method: http.MethodPost, URL: url, Headers: headers, Body: body, Assert: assert}}, nil
+}
+
+type graphQLResponse struct {
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func (g *GraphQLSpec) Execute(vu *VirtualUser, start time.Time, results chan<- Result) {
+	res, body := do(vu.Client, g.request, start, true)
+	if res.Error == "" {
+		var resp graphQLResponse
+		if json.Unmarshal(body, &resp) == nil && len(resp.Errors) > 0 {
+			res.GraphQLErrors = len(resp.Errors)
+			res.ErrorMessage = resp.Errors[0].Message
+		}
+	}
+	results <- res
+}
+
+// maxGraphQLMessages bounds the distinct error messages kept in the report;
+// the rest are counted together.
+const (
+	maxGraphQLMessages  = 20
+	otherGraphQLMessage = "(other)"
+)
+
+type GraphQLStats struct {
+	Responses int            `json:"Responses"`
+	Errors    int            `json:"Errors"`
+	Messages  map[string]int `json:"Messages,omitempty"`
+}
+
+func (s *GraphQLStats) record(res Result) {
+	if res.GraphQLErrors == 0 {
+		return
+	}
+	s.Responses++
+	s.Errors += res.GraphQLErrors
+	s.add(res.ErrorMessage, 1)
+}
+
+func (s *GraphQLStats) merge(other GraphQLStats) {
+	s.Responses += other.Responses
+	s.Errors += other.Errors
+	for msg, count := range other.Messages {
+		s.add(msg, count)
+	}
+}
+
+// add counts a message, folding it into "(other)" once the bound is reached.
+// "(other)" itself does not count toward the bound.
+func (s *GraphQLStats) add(msg string, count int) {
+	if s.Messages == nil {
+		s.Messages = make(map[string]int)
+	}
+	distinct := len(s.Messages)
+	if _, ok := s.Messages[otherGraphQLMessage]; ok {
+		distinct--
+	}
+	if _, ok := s.Messages[msg]; !ok && msg != otherGraphQLMessage && distinct >= maxGraphQLMessages {
+		msg = otherGraphQLMessage
+	}
+	s.Messages[msg] += count
+}
+
+func writeGraphQLCSV(w *csv.Writer, stats *GraphQLStats) {
+	if stats == nil {
+		return
+	}
+	w.Write(nil)
+	w.Write([]string{"GraphQLResponsesWithErrors", "GraphQLErrors"})
+	w.Write([]string{strconv.Itoa(stats.Responses), strconv.Itoa(stats.Errors)})
+	if len(stats.Messages) == 0 {
+		return
+	}
+	w.Write(nil)
+	w.Write([]string{"GraphQLError", "Count"})
+	for msg, count := range stats.Messages {
+		w.Write([]string{msg, strconv.Itoa(count)})
+	}
+}
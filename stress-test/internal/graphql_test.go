@@ -0,0 +1,145 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// graphQLServer answers 200 to every query and echoes the error messages
+// requested through the "fail" variable.
+type graphQLServer struct {
+	mu          sync.Mutex
+	doc         map[string]any
+	contentType string
+}
+
+func (g *graphQLServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var doc map[string]any
+	json.NewDecoder(r.Body).Decode(&doc)
+	g.mu.Lock()
+	g.doc, g.contentType = doc, r.Header.Get("Content-Type")
+	g.mu.Unlock()
+
+	vars, _ := doc["variables"].(map[string]any)
+	fail, _ := vars["fail"].([]any)
+	if len(fail) == 0 {
+		fmt.Fprint(w, `{"data":{"patient":{"name":"Ana"}}}`)
+		return
+	}
+	var errs []map[string]any
+	for _, msg := range fail {
+		errs = append(errs, map[string]any{"message": msg})
+	}
+	json.NewEncoder(w).Encode(map[string]any{"data": nil, "errors": errs})
+}
+
+func graphQL(t *testing.T, spec *GraphQLSpec) Result {
+	t.Helper()
+	results := make(chan Result, 1)
+	spec.Execute(&VirtualUser{Client: http.DefaultClient}, time.Now(), results)
+	return <-results
+}
+
+func TestGraphQLRequestBody(t *testing.T) {
+	gs := &graphQLServer{}
+	srv := httptest.NewServer(gs)
+	defer srv.Close()
+
+	query := "query Patient($id: ID!) { patient(id: $id) { name } }"
+	cases := []struct {
+		name      string
+		operation string
+		variables string
+		want      map[string]any
+	}{
+		{"query only", "", "", map[string]any{"query": query}},
+		{"operation and variables", "Patient", `{"id": "7"}`,
+			map[string]any{"query": query, "operationName": "Patient", "variables": map[string]any{"id": "7"}}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec, err := NewGraphQLSpec(srv.URL, query, tc.operation, []byte(tc.variables), http.Header{}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res := graphQL(t, spec); res.StatusCode != 200 {
+				t.Fatalf("Expected 200, got %+v", res)
+			}
+			gs.mu.Lock()
+			defer gs.mu.Unlock()
+			if !reflect.DeepEqual(gs.doc, tc.want) {
+				t.Errorf("Expected body %v, got %v", tc.want, gs.doc)
+			}
+			if gs.contentType != "application/json" {
+				t.Errorf("Expected a JSON content type, got %q", gs.contentType)
+			}
+		})
+	}
+
+	if _, err := NewGraphQLSpec(srv.URL, query, "", []byte(`[1]`), http.Header{}, nil); err == nil {
+		t.Error("Expected variables that are not an object to be rejected")
+	}
+}
+
+func TestGraphQLErrorsCountAsFailures(t *testing.T) {
+	srv := httptest.NewServer(&graphQLServer{})
+	defer srv.Close()
+
+	rec := NewRecorder()
+	rec.Protocol = ProtocolGraphQL
+	for _, vars := range []string{
+		`{}`,
+		`{"fail": ["patient not found"]}`,
+		`{"fail": ["patient not found", "unauthorized"]}`,
+		`{"fail": ["unauthorized"]}`,
+	} {
+		spec, err := NewGraphQLSpec(srv.URL, "{ patient { name } }", "", []byte(vars), http.Header{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := graphQL(t, spec)
+		if res.StatusCode != 200 {
+			t.Fatalf("Expected every response to be a 200, got %+v", res)
+		}
+		rec.Record(res)
+	}
+
+	report := rec.Report(time.Second, false)
+	if report.SuccessCount != 1 || report.FailedCount != 3 {
+		t.Errorf("Expected responses with errors to fail despite the 200, got %d successful and %d failed", report.SuccessCount, report.FailedCount)
+	}
+	want := &GraphQLStats{Responses: 3, Errors: 4, Messages: map[string]int{"patient not found": 2, "unauthorized": 1}}
+	if !reflect.DeepEqual(report.GraphQL, want) {
+		t.Errorf("Expected GraphQL stats %+v, got %+v", want, report.GraphQL)
+	}
+
+	rec.Protocol = ProtocolHTTP
+	if report := rec.Report(time.Second, false); report.GraphQL != nil {
+		t.Errorf("Expected no GraphQL stats outside GraphQL runs, got %+v", report.GraphQL)
+	}
+}
+
+func TestGraphQLStatsBoundsMessages(t *testing.T) {
+	var stats GraphQLStats
+	for i := 0; i < maxGraphQLMessages+5; i++ {
+		stats.record(Result{GraphQLErrors: 1, ErrorMessage: fmt.Sprintf("error %d", i)})
+	}
+	stats.record(Result{GraphQLErrors: 1, ErrorMessage: "error 0"})
+
+	if len(stats.Messages) != maxGraphQLMessages+1 || stats.Messages["(other)"] != 5 || stats.Messages["error 0"] != 2 {
+		t.Errorf("Expected %d messages plus 5 others, got %v", maxGraphQLMessages, stats.Messages)
+	}
+
+	var merged GraphQLStats
+	merged.merge(stats)
+	merged.merge(GraphQLStats{Responses: 1, Errors: 2, Messages: map[string]int{"new": 1}})
+	if merged.Responses != stats.Responses+1 || merged.Errors != stats.Errors+2 || merged.Messages["(other)"] != 6 {
+		t.Errorf("Expected merged stats to stay bounded, got %+v", merged)
+	}
+}
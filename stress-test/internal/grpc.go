@@ -0,0 +1,251 @@
+package internal
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+type GRPCOptions struct {
+	// Target is host:port; a grpcs:// prefix enables TLS and grpc:// is
+	// accepted for plaintext.
+	Target string
+	// Method is "package.Service/Method" (or "package.Service.Method").
+	Method      string
+	ProtoFiles  []string
+	ImportPaths []string
+	Protoset    string
+	Body        []byte
+	Headers     http.Header
+	Insecure    bool
+	Timeout     time.Duration
+}
+
+// GRPCSpec calls one unary method with a JSON-encoded request message. The
+// method descriptor comes from a .proto file, a compiled protoset or, when
+// neither is given, the server reflection service.
+type GRPCSpec struct {
+	conn       *grpc.ClientConn
+	fullMethod string
+	method     protoreflect.MethodDescriptor
+	request    proto.Message
+	metadata   metadata.MD
+	timeout    time.Duration
+}
+
+type descriptorResolver interface {
+	FindDescriptorByName(protoreflect.FullName) (protoreflect.Descriptor, error)
+}
+
+func NewGRPCSpec(ctx context.Context, opts GRPCOptions) (*GRPCSpec, error) {
+	target, useTLS := opts.Target, false
+	if rest, ok := strings.CutPrefix(target, "grpcs://"); ok {
+		target, useTLS = rest, true
+	}
+	target = strings.TrimPrefix(target, "grpc://")
+
+	creds := insecure.NewCredentials()
+	if useTLS {
+		creds = credentials.NewTLS(&tls.Config{InsecureSkipVerify: opts.Insecure})
+	}
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, err
+	}
+
+	service, name, err := splitMethod(opts.Method)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resolver, err := loadDescriptors(ctx, conn, service, opts)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	desc, err := resolver.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("service %s: %w", service, err)
+	}
+	svc, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		conn.Close()
+		return nil, fmt.Errorf("%s is not a service", service)
+	}
+	method := svc.Methods().ByName(protoreflect.Name(name))
+	if method == nil {
+		conn.Close()
+		return nil, fmt.Errorf("service %s has no method %s", service, name)
+	}
+	if method.IsStreamingClient() || method.IsStreamingServer() {
+		conn.Close()
+		return nil, fmt.Errorf("%s is a streaming method, only unary methods are supported", opts.Method)
+	}
+
+	request := dynamicpb.NewMessage(method.Input())
+	if len(opts.Body) > 0 {
+		if err := protojson.Unmarshal(opts.Body, request); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("request message: %w", err)
+		}
+	}
+
+	md := metadata.MD{}
+	for key, values := range opts.Headers {
+		md.Append(key, values...)
+	}
+
+	return &GRPCSpec{
+		conn:       conn,
+		fullMethod: "/" + service + "/" + name,
+		method:     method,
+		request:    request,
+		metadata:   md,
+		timeout:    opts.Timeout,
+	}, nil
+}
+
+func splitMethod(method string) (string, string, error) {
+	method = strings.TrimPrefix(method, "/")
+	i := strings.LastIndexAny(method, "/.")
+	if i <= 0 || i == len(method)-1 {
+		return "", "", fmt.Errorf("invalid method %q, expected package.Service/Method", method)
+	}
+	return method[:i], method[i+1:], nil
+}
+
+func loadDescriptors(ctx context.Context, conn *grpc.ClientConn, service string, opts GRPCOptions) (descriptorResolver, error) {
+	switch {
+	case len(opts.ProtoFiles) > 0:
+		compiler := protocompile.Compiler{
+			Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{ImportPaths: opts.ImportPaths}),
+		}
+		files, err := compiler.Compile(ctx, opts.ProtoFiles...)
+		if err != nil {
+			return nil, err
+		}
+		return files.AsResolver(), nil
+	case opts.Protoset != "":
+		data, err := os.ReadFile(opts.Protoset)
+		if err != nil {
+			return nil, err
+		}
+		var set descriptorpb.FileDescriptorSet
+		if err := proto.Unmarshal(data, &set); err != nil {
+			return nil, fmt.Errorf("protoset %s: %w", opts.Protoset, err)
+		}
+		return protodesc.NewFiles(&set)
+	default:
+		return reflectDescriptors(ctx, conn, service)
+	}
+}
+
+// reflectDescriptors asks the server reflection service for the file that
+// defines service and, one by one, every dependency it does not have yet.
+func reflectDescriptors(ctx context.Context, conn *grpc.ClientConn, service string) (descriptorResolver, error) {
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("server reflection: %w", err)
+	}
+	defer stream.CloseSend()
+
+	files := make(map[string]*descriptorpb.FileDescriptorProto)
+	ask := func(req *reflectionpb.ServerReflectionRequest) error {
+		if err := stream.Send(req); err != nil {
+			return fmt.Errorf("server reflection: %w", err)
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return fmt.Errorf("server reflection: %w", err)
+		}
+		if e := resp.GetErrorResponse(); e != nil {
+			return fmt.Errorf("server reflection: %s", e.GetErrorMessage())
+		}
+		for _, raw := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+			fd := &descriptorpb.FileDescriptorProto{}
+			if err := proto.Unmarshal(raw, fd); err != nil {
+				return fmt.Errorf("server reflection: %w", err)
+			}
+			files[fd.GetName()] = fd
+		}
+		return nil
+	}
+
+	err = ask(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: service},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var missing []string
+		for _, fd := range files {
+			for _, dep := range fd.GetDependency() {
+				if _, ok := files[dep]; !ok {
+					missing = append(missing, dep)
+				}
+			}
+		}
+		if len(missing) == 0 {
+			break
+		}
+		for _, name := range missing {
+			err := ask(&reflectionpb.ServerReflectionRequest{
+				MessageRequest: &reflectionpb.ServerReflectionRequest_FileByFilename{FileByFilename: name},
+			})
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := files[name]; !ok {
+				return nil, fmt.Errorf("server reflection: dependency %s not returned", name)
+			}
+		}
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, fd := range files {
+		set.File = append(set.File, fd)
+	}
+	return protodesc.NewFiles(set)
+}
+
+func (g *GRPCSpec) Execute(vu *VirtualUser, start time.Time, results chan<- Result) {
+	ctx := metadata.NewOutgoingContext(context.Background(), g.metadata)
+	if g.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.timeout)
+		defer cancel()
+	}
+
+	resp := dynamicpb.NewMessage(g.method.Output())
+	err := g.conn.Invoke(ctx, g.fullMethod, g.request, resp)
+	res := Result{StatusCode: int(status.Code(err)), Duration: time.Since(start)}
+	if err != nil {
+		res.Error = ClassifyGRPCError(err)
+		res.ErrorMessage = status.Convert(err).Message()
+	}
+	results <- res
+}
+
+func (g *GRPCSpec) Close() error {
+	return g.conn.Close()
+}
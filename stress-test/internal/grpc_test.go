@@ -0,0 +1,227 @@
+package internal
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const healthProto = `syntax = "proto3";
+package grpc.health.v1;
+
+message HealthCheckRequest { string service = 1; }
+message HealthCheckResponse {
+  enum ServingStatus { UNKNOWN = 0; SERVING = 1; NOT_SERVING = 2; SERVICE_UNKNOWN = 3; }
+  ServingStatus status = 1;
+}
+service Health {
+  rpc Check(HealthCheckRequest) returns (HealthCheckResponse);
+  rpc Watch(HealthCheckRequest) returns (stream HealthCheckResponse);
+}
+`
+
+// healthServer serves the standard health service with reflection enabled
+// and records the metadata of the last call.
+type healthServer struct {
+	addr string
+
+	mu sync.Mutex
+	md metadata.MD
+}
+
+func startHealthServer(t *testing.T) *healthServer {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs := &healthServer{addr: lis.Addr().String()}
+	srv := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		hs.mu.Lock()
+		hs.md = md
+		hs.mu.Unlock()
+		return handler(ctx, req)
+	}))
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	reflection.Register(srv)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return hs
+}
+
+func (hs *healthServer) metadata(key string) []string {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	return hs.md.Get(key)
+}
+
+func descriptorFiles(t *testing.T) (protoDir, protoset string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "health.proto"), []byte(healthProto), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	set := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{
+		protodesc.ToFileDescriptorProto(healthpb.File_grpc_health_v1_health_proto),
+	}}
+	data, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	protoset = filepath.Join(dir, "health.protoset")
+	if err := os.WriteFile(protoset, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir, protoset
+}
+
+func call(spec *GRPCSpec) Result {
+	results := make(chan Result, 1)
+	spec.Execute(nil, time.Now(), results)
+	return <-results
+}
+
+func TestGRPCSpecDescriptorSources(t *testing.T) {
+	hs := startHealthServer(t)
+	dir, protoset := descriptorFiles(t)
+
+	cases := []struct {
+		name string
+		opts GRPCOptions
+	}{
+		{"reflection", GRPCOptions{}},
+		{"proto file", GRPCOptions{ProtoFiles: []string{"health.proto"}, ImportPaths: []string{dir}}},
+		{"protoset", GRPCOptions{Protoset: protoset}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := tc.opts
+			opts.Target = "grpc://" + hs.addr
+			opts.Method = "grpc.health.v1.Health/Check"
+			opts.Body = []byte(`{"service": ""}`)
+			opts.Headers = map[string][]string{"X-Tenant": {"acme"}}
+			spec, err := NewGRPCSpec(context.Background(), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer spec.Close()
+
+			if res := call(spec); res.StatusCode != int(codes.OK) || res.Error != "" {
+				t.Errorf("Expected OK, got %+v", res)
+			}
+			if got := hs.metadata("x-tenant"); len(got) != 1 || got[0] != "acme" {
+				t.Errorf("Expected headers sent as metadata, got %q", got)
+			}
+		})
+	}
+}
+
+func TestGRPCSpecStatusMapping(t *testing.T) {
+	hs := startHealthServer(t)
+	_, protoset := descriptorFiles(t)
+	// A protoset needs no connection, so a spec can target an address nobody
+	// serves.
+	newSpec := func(target, body string) *GRPCSpec {
+		spec, err := NewGRPCSpec(context.Background(), GRPCOptions{Target: target, Method: "grpc.health.v1.Health.Check", Protoset: protoset, Body: []byte(body)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { spec.Close() })
+		return spec
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := lis.Addr().String()
+	lis.Close()
+
+	cases := []struct {
+		name     string
+		spec     *GRPCSpec
+		code     codes.Code
+		category ErrorCategory
+	}{
+		{"ok", newSpec(hs.addr, `{"service": ""}`), codes.OK, ""},
+		{"unknown service", newSpec(hs.addr, `{"service": "missing"}`), codes.NotFound, "not_found"},
+		{"nothing listening", newSpec(closed, `{}`), codes.Unavailable, "unavailable"},
+	}
+
+	rec := NewRecorder()
+	rec.Protocol = ProtocolGRPC
+	for _, tc := range cases {
+		res := call(tc.spec)
+		if res.StatusCode != int(tc.code) || res.Error != tc.category {
+			t.Errorf("%s: expected %v/%q, got %d/%q", tc.name, tc.code, tc.category, res.StatusCode, res.Error)
+		}
+		rec.Record(res)
+	}
+
+	report := rec.Report(time.Second, false)
+	if report.SuccessCount != 1 || report.FailedCount != 2 || report.Errors["not_found"].Count != 1 || report.Errors["unavailable"].Count != 1 {
+		t.Errorf("Expected gRPC codes counted by the recorder, got %+v", report)
+	}
+	if name := report.Protocol.StatusName(int(codes.NotFound)); name != "NotFound" {
+		t.Errorf("Expected gRPC status names, got %q", name)
+	}
+}
+
+func TestNewGRPCSpecErrors(t *testing.T) {
+	hs := startHealthServer(t)
+	cases := []struct {
+		name   string
+		method string
+		body   string
+	}{
+		{"invalid method", "Check", ""},
+		{"unknown service", "grpc.health.v1.Missing/Check", ""},
+		{"unknown method", "grpc.health.v1.Health/Nope", ""},
+		{"streaming method", "grpc.health.v1.Health/Watch", ""},
+		{"unknown field", "grpc.health.v1.Health/Check", `{"nope": 1}`},
+		{"invalid json", "grpc.health.v1.Health/Check", `{`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec, err := NewGRPCSpec(context.Background(), GRPCOptions{Target: hs.addr, Method: tc.method, Body: []byte(tc.body)})
+			if err == nil {
+				spec.Close()
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestSplitMethod(t *testing.T) {
+	cases := []struct {
+		in, service, method string
+		ok                  bool
+	}{
+		{"pkg.Service/Method", "pkg.Service", "Method", true},
+		{"/pkg.Service/Method", "pkg.Service", "Method", true},
+		{"pkg.Service.Method", "pkg.Service", "Method", true},
+		{"Method", "", "", false},
+		{"pkg.Service/", "", "", false},
+	}
+	for _, tc := range cases {
+		service, method, err := splitMethod(tc.in)
+		if (err == nil) != tc.ok || service != tc.service || method != tc.method {
+			t.Errorf("Expected %q to split into %q %q (ok %v), got %q %q %v", tc.in, tc.service, tc.method, tc.ok, service, method, err)
+		}
+	}
+}
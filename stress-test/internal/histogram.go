@@ -2,36 +2,196 @@ package internal
 
 import (
 	"fmt"
+	"math"
+	"math/bits"
+	"sort"
 	"strings"
 	"time"
 )
 
-func BuildHistogram(latencies []time.Duration, bucketSize time.Duration) ([]Bucket, string) {
-	if len(latencies) == 0 {
-		return nil, "No latencies recorded"
+// The histogram follows the HdrHistogram layout: values are grouped in
+// power-of-two buckets, each split in linear sub-buckets, which keeps three
+// significant digits of precision from a microsecond up to an hour in a
+// bounded number of counters, however many values are recorded.
+const (
+	histUnitShift      = 10 // values are counted in units of 1024ns
+	histSubBucketBits  = 11
+	histSubBucketCount = 1 << histSubBucketBits
+	histSubBucketHalf  = histSubBucketCount / 2
+	histHighest        = time.Hour
+)
+
+// Histogram records latencies in constant memory. Counts is sparse and keyed
+// by bucket index so histograms from several runs or agents can be merged.
+type Histogram struct {
+	Counts map[int]int64 `json:"Counts"`
+	Total  int64         `json:"Total"`
+	Sum    time.Duration `json:"Sum"`
+	Min    time.Duration `json:"Min"`
+	Max    time.Duration `json:"Max"`
+}
+
+func NewHistogram() *Histogram {
+	return &Histogram{Counts: make(map[int]int64)}
+}
+
+func (h *Histogram) Record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	if d > histHighest {
+		d = histHighest
+	}
+	h.Counts[histIndex(d)]++
+	if h.Total == 0 || d < h.Min {
+		h.Min = d
+	}
+	if d > h.Max {
+		h.Max = d
+	}
+	h.Total++
+	h.Sum += d
+}
+
+func (h *Histogram) Merge(other *Histogram) {
+	if other == nil || other.Total == 0 {
+		return
+	}
+	for i, c := range other.Counts {
+		h.Counts[i] += c
+	}
+	if h.Total == 0 || other.Min < h.Min {
+		h.Min = other.Min
+	}
+	if other.Max > h.Max {
+		h.Max = other.Max
+	}
+	h.Total += other.Total
+	h.Sum += other.Sum
+}
+
+func (h *Histogram) Mean() time.Duration {
+	if h.Total == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Total)
+}
+
+// Percentile returns the smallest recorded value that at least p percent of
+// the values are less than or equal to, within the bucket precision. The
+// result always lies in [Min, Max], and p0 and p100 are exactly Min and Max.
+func (h *Histogram) Percentile(p float64) time.Duration {
+	if h.Total == 0 {
+		return 0
+	}
+	if p <= 0 {
+		return h.Min
+	}
+	rank := int64(math.Ceil(p / 100 * float64(h.Total)))
+	if rank < 1 {
+		rank = 1
+	}
+
+	var seen int64
+	for _, i := range h.indexes() {
+		seen += h.Counts[i]
+		if seen >= rank {
+			return h.clamp(histHighestEquivalent(i))
+		}
 	}
+	return h.Max
+}
 
-	var maxLatency time.Duration
-	for _, l := range latencies {
-		if l > maxLatency {
-			maxLatency = l
+func (h *Histogram) indexes() []int {
+	indexes := make([]int, 0, len(h.Counts))
+	for i, c := range h.Counts {
+		if c > 0 {
+			indexes = append(indexes, i)
 		}
 	}
+	sort.Ints(indexes)
+	return indexes
+}
 
-	numBuckets := int(maxLatency/bucketSize) + 1
-	buckets := make([]Bucket, numBuckets)
-	for i := 0; i < numBuckets; i++ {
-		from := time.Duration(i) * bucketSize
-		to := from + bucketSize
-		buckets[i] = Bucket{From: from, To: to, Count: 0}
+// clamp keeps bucket edges inside the exact recorded range, so p100 is Max.
+func (h *Histogram) clamp(d time.Duration) time.Duration {
+	if d > h.Max {
+		return h.Max
 	}
+	if d < h.Min {
+		return h.Min
+	}
+	return d
+}
 
-	for _, l := range latencies {
-		idx := int(l / bucketSize)
-		if idx >= len(buckets) {
-			idx = len(buckets) - 1
+func histIndex(d time.Duration) int {
+	v := uint64(d) >> histUnitShift
+	bucket := bits.Len64(v|(histSubBucketCount-1)) - histSubBucketBits
+	sub := int(v >> bucket)
+	return (bucket+1)*histSubBucketHalf + sub - histSubBucketHalf
+}
+
+func histLowestEquivalent(i int) time.Duration {
+	bucket := i/histSubBucketHalf - 1
+	sub := i%histSubBucketHalf + histSubBucketHalf
+	if bucket < 0 {
+		sub -= histSubBucketHalf
+		bucket = 0
+	}
+	return time.Duration(uint64(sub)<<bucket) << histUnitShift
+}
+
+func histHighestEquivalent(i int) time.Duration {
+	bucket := i/histSubBucketHalf - 1
+	if bucket < 0 {
+		bucket = 0
+	}
+	return histLowestEquivalent(i) + time.Duration(uint64(1)<<bucket)<<histUnitShift - 1
+}
+
+// logSteps are the 1-2-5 multipliers of each decade in BuildHistogram.
+var logSteps = []time.Duration{1, 2, 5}
+
+// BuildHistogram groups the recorded latencies in logarithmic buckets
+// (1ms, 2ms, 5ms, 10ms, ...) spanning the recorded range.
+func BuildHistogram(h *Histogram) ([]Bucket, string) {
+	if h == nil || h.Total == 0 {
+		return nil, "No latencies recorded"
+	}
+
+	var edges []time.Duration
+	for decade := time.Microsecond; len(edges) == 0 || edges[len(edges)-1] <= h.Max; decade *= 10 {
+		for _, step := range logSteps {
+			edges = append(edges, decade*step)
+		}
+	}
+	// Start from the edge right below the fastest value.
+	first := 0
+	for first+1 < len(edges) && edges[first+1] <= h.Min {
+		first++
+	}
+	edges = edges[first:]
+	if edges[0] > h.Min {
+		edges = append([]time.Duration{0}, edges...)
+	}
+
+	var buckets []Bucket
+	for i := 0; i+1 < len(edges); i++ {
+		buckets = append(buckets, Bucket{From: edges[i], To: edges[i+1]})
+	}
+	// A histogram bucket can straddle an edge, e.g. 1ms falls in the bucket
+	// starting at 999.4µs; its highest equivalent keeps such values from
+	// being reported below the edge.
+	for _, i := range h.indexes() {
+		v := h.clamp(histHighestEquivalent(i))
+		b := sort.Search(len(buckets), func(b int) bool { return v < buckets[b].To })
+		if b == len(buckets) {
+			b--
 		}
-		buckets[idx].Count++
+		buckets[b].Count += int(h.Counts[i])
+	}
+	for len(buckets) > 1 && buckets[len(buckets)-1].Count == 0 {
+		buckets = buckets[:len(buckets)-1]
 	}
 
 	var sb strings.Builder
@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func histogramOf(values ...time.Duration) *Histogram {
+	h := NewHistogram()
+	for _, v := range values {
+		h.Record(v)
+	}
+	return h
+}
+
+func TestBuildHistogramSampleCounts(t *testing.T) {
+	ms := time.Millisecond
+	cases := []struct {
+		name   string
+		values []time.Duration
+		want   []Bucket
+	}{
+		{"no samples", nil, nil},
+		{"one sample", []time.Duration{3 * ms}, []Bucket{{From: 2 * ms, To: 5 * ms, Count: 1}}},
+		{"zero latency", []time.Duration{0}, []Bucket{{From: 0, To: time.Microsecond, Count: 1}}},
+		{"five samples", []time.Duration{3 * ms, 4 * ms, 8 * ms, 12 * ms, 30 * ms}, []Bucket{
+			{From: 2 * ms, To: 5 * ms, Count: 2},
+			{From: 5 * ms, To: 10 * ms, Count: 1},
+			{From: 10 * ms, To: 20 * ms, Count: 1},
+			{From: 20 * ms, To: 50 * ms, Count: 1},
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			buckets, _ := BuildHistogram(histogramOf(tc.values...))
+			if len(buckets) != len(tc.want) {
+				t.Fatalf("Expected %v, got %v", tc.want, buckets)
+			}
+			for i := range buckets {
+				if buckets[i] != tc.want[i] {
+					t.Errorf("Expected bucket %d to be %v, got %v", i, tc.want[i], buckets[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBuildHistogramEdges(t *testing.T) {
+	ms := time.Millisecond
+	cases := []struct {
+		value time.Duration
+		from  time.Duration
+	}{
+		{500 * time.Microsecond, 500 * time.Microsecond},
+		{999 * time.Microsecond, 500 * time.Microsecond},
+		{ms, ms},
+		{2 * ms, 2 * ms},
+		{10 * ms, 10 * ms},
+	}
+
+	for _, tc := range cases {
+		// The extra samples keep the value from being clamped to Min or Max.
+		buckets, _ := BuildHistogram(histogramOf(100*time.Microsecond, tc.value, time.Second))
+		found := false
+		for _, b := range buckets {
+			if b.From == tc.from && b.Count == 1 {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected %v counted in the bucket from %v, got %v", tc.value, tc.from, buckets)
+		}
+	}
+}
+
+func TestPercentileBounds(t *testing.T) {
+	h := histogramOf(500*time.Nanosecond, 2*time.Millisecond, 7*time.Millisecond, 7*time.Millisecond, 40*time.Millisecond)
+
+	if p := h.Percentile(0); p != h.Min {
+		t.Errorf("Expected p0 to be Min %v, got %v", h.Min, p)
+	}
+	if p := h.Percentile(100); p != h.Max {
+		t.Errorf("Expected p100 to be Max %v, got %v", h.Max, p)
+	}
+
+	prev := time.Duration(0)
+	for p := 0.0; p <= 100; p += 0.5 {
+		v := h.Percentile(p)
+		if v < prev || v < h.Min || v > h.Max {
+			t.Fatalf("Expected monotonic percentiles within [%v, %v], got p%.1f = %v after %v", h.Min, h.Max, p, v, prev)
+		}
+		prev = v
+	}
+
+	if p := NewHistogram().Percentile(50); p != 0 {
+		t.Errorf("Expected 0 for an empty histogram, got %v", p)
+	}
+	if p := histogramOf(3 * time.Millisecond).Percentile(50); p != 3*time.Millisecond {
+		t.Errorf("Expected the only sample for a single value, got %v", p)
+	}
+}
+
+func TestHistogramMerge(t *testing.T) {
+	ms := time.Millisecond
+	a := histogramOf(2*ms, 5*ms, 9*ms)
+	b := histogramOf(time.Microsecond, 40*ms)
+	all := histogramOf(2*ms, 5*ms, 9*ms, time.Microsecond, 40*ms)
+
+	a.Merge(b)
+	a.Merge(nil)
+	a.Merge(NewHistogram())
+	if a.Total != all.Total || a.Sum != all.Sum || a.Min != all.Min || a.Max != all.Max {
+		t.Errorf("Expected merged totals %+v, got %+v", all, a)
+	}
+	for _, p := range []float64{0, 50, 90, 99, 100} {
+		if a.Percentile(p) != all.Percentile(p) {
+			t.Errorf("Expected p%v %v, got %v", p, all.Percentile(p), a.Percentile(p))
+		}
+	}
+
+	empty := NewHistogram()
+	empty.Merge(b)
+	if empty.Min != time.Microsecond || empty.Max != 40*ms {
+		t.Errorf("Expected an empty histogram to take the merged range, got %v - %v", empty.Min, empty.Max)
+	}
+}
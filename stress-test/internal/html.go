@@ -0,0 +1,196 @@
+package internal
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	chartWidth   = 800
+	chartHeight  = 220
+	chartPadding = 40
+)
+
+var chartColors = []string{"#2563eb", "#dc2626", "#16a34a", "#d97706", "#7c3aed", "#0891b2", "#db2777"}
+
+type chartSeries struct {
+	Name   string
+	Color  string
+	Points string
+}
+
+type chart struct {
+	Title  string
+	Unit   string
+	Max    string
+	End    string
+	Width  int
+	Height int
+	Pad    int
+	Right  int
+	Bottom int
+	Series []chartSeries
+}
+
+// newChart plots each named series against the interval offsets, scaling
+// both axes to the data.
+func newChart(title, unit string, elapsed []time.Duration, names []string, values [][]float64, format func(float64) string) chart {
+	var max float64
+	for _, vs := range values {
+		for _, v := range vs {
+			if v > max {
+				max = v
+			}
+		}
+	}
+	if max == 0 {
+		max = 1
+	}
+	var end time.Duration
+	if len(elapsed) > 0 {
+		end = elapsed[len(elapsed)-1]
+	}
+
+	c := chart{Title: title, Unit: unit, Max: format(max), End: end.Round(time.Second).String(), Width: chartWidth, Height: chartHeight,
+		Pad: chartPadding, Right: chartWidth - chartPadding, Bottom: chartHeight - chartPadding}
+	plotW := float64(chartWidth - 2*chartPadding)
+	plotH := float64(chartHeight - 2*chartPadding)
+	for i, name := range names {
+		var pts []string
+		for j, v := range values[i] {
+			x := float64(chartPadding)
+			if end > 0 {
+				x += plotW * float64(elapsed[j]) / float64(end)
+			}
+			y := float64(chartHeight-chartPadding) - plotH*v/max
+			pts = append(pts, fmt.Sprintf("%.1f,%.1f", x, y))
+		}
+		c.Series = append(c.Series, chartSeries{Name: name, Color: chartColors[i%len(chartColors)], Points: strings.Join(pts, " ")})
+	}
+	return c
+}
+
+func reportCharts(series []IntervalStats) []chart {
+	elapsed := make([]time.Duration, len(series))
+	rps := make([]float64, len(series))
+	failed := make([]float64, len(series))
+	var p50, p95, p99 []float64
+	codeSet := make(map[int]bool)
+	for i, s := range series {
+		elapsed[i] = s.Elapsed
+		rps[i] = s.RPS
+		failed[i] = float64(s.Failed)
+		p50 = append(p50, ms(s.P50))
+		p95 = append(p95, ms(s.P95))
+		p99 = append(p99, ms(s.P99))
+		for code := range s.StatusCodes {
+			codeSet[code] = true
+		}
+	}
+
+	var codes []int
+	for code := range codeSet {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	codeNames := make([]string, len(codes))
+	codeValues := make([][]float64, len(codes))
+	for i, code := range codes {
+		codeNames[i] = fmt.Sprint(code)
+		for _, s := range series {
+			codeValues[i] = append(codeValues[i], float64(s.StatusCodes[code]))
+		}
+	}
+
+	count := func(v float64) string { return fmt.Sprintf("%.0f", v) }
+	millis := func(v float64) string { return fmt.Sprintf("%.1fms", v) }
+	return []chart{
+		newChart("Throughput", "requests/s", elapsed, []string{"rps", "failed"}, [][]float64{rps, failed}, count),
+		newChart("Latency", "per interval", elapsed, []string{"p50", "p95", "p99"}, [][]float64{p50, p95, p99}, millis),
+		newChart("Status codes", "responses per interval", elapsed, codeNames, codeValues, count),
+	}
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+var htmlReport = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Stress Test Report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #111; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+td, th { border: 1px solid #ddd; padding: 4px 10px; text-align: left; }
+svg { background: #fafafa; border: 1px solid #ddd; }
+.legend span { display: inline-block; margin-right: 1em; }
+</style>
+</head>
+<body>
+<h1>Stress Test Report</h1>
+{{with .Report}}
+<table>
+<tr><th>Total requests</th><td>{{.TotalRequests}}</td></tr>
+<tr><th>Successful ({{.SuccessName}})</th><td>{{.SuccessCount}}</td></tr>
+<tr><th>Failed</th><td>{{.FailedCount}}</td></tr>
+<tr><th>Duration</th><td>{{.Duration}}</td></tr>
+</table>
+<table>
+<tr><th>Min</th><th>Mean</th><th>P50</th><th>P75</th><th>P90</th><th>P95</th><th>P99</th><th>P99.9</th><th>Max</th></tr>
+<tr><td>{{.Min}}</td><td>{{.Mean}}</td><td>{{.P50}}</td><td>{{.P75}}</td><td>{{.P90}}</td><td>{{.P95}}</td><td>{{.P99}}</td><td>{{.P999}}</td><td>{{.Max}}</td></tr>
+</table>
+<table>
+<tr><th>Status code</th><th>Count</th></tr>
+{{range $code, $count := .StatusCodes}}<tr><td>{{$.Report.Protocol.StatusName $code}}</td><td>{{$count}}</td></tr>
+{{end}}</table>
+{{if .Errors}}<table>
+<tr><th>Error</th><th>Count</th><th>Sample</th></tr>
+{{range $category, $summary := .Errors}}<tr><td>{{$category}}</td><td>{{$summary.Count}}</td><td>{{$summary.Sample}}</td></tr>
+{{end}}</table>{{end}}
+{{if .Assertions}}<table>
+<tr><th>Failed assertion</th><th>Responses</th></tr>
+{{range $name, $count := .Assertions}}<tr><td>{{$name}}</td><td>{{$count}}</td></tr>
+{{end}}</table>{{end}}
+{{with .GraphQL}}<table>
+<tr><th>GraphQL responses with errors</th><th>GraphQL errors</th></tr>
+<tr><td>{{.Responses}}</td><td>{{.Errors}}</td></tr>
+</table>
+{{if .Messages}}<table>
+<tr><th>GraphQL error</th><th>Count</th></tr>
+{{range $msg, $count := .Messages}}<tr><td>{{$msg}}</td><td>{{$count}}</td></tr>
+{{end}}</table>{{end}}{{end}}
+{{if .Phases}}<table>
+<tr><th>Phase</th><th>Count</th><th>Mean</th><th>P50</th><th>P90</th><th>P95</th><th>P99</th><th>Max</th></tr>
+{{range .Phases}}<tr><td>{{.Phase}}</td><td>{{.Count}}</td><td>{{.Mean}}</td><td>{{.P50}}</td><td>{{.P90}}</td><td>{{.P95}}</td><td>{{.P99}}</td><td>{{.Max}}</td></tr>
+{{end}}</table>{{end}}
+{{end}}
+{{range .Charts}}
+<h2>{{.Title}} <small>({{.Unit}})</small></h2>
+<svg width="{{.Width}}" height="{{.Height}}" viewBox="0 0 {{.Width}} {{.Height}}">
+<line x1="{{.Pad}}" y1="{{.Pad}}" x2="{{.Pad}}" y2="{{.Bottom}}" stroke="#999"/>
+<line x1="{{.Pad}}" y1="{{.Bottom}}" x2="{{.Right}}" y2="{{.Bottom}}" stroke="#999"/>
+<text x="4" y="{{.Pad}}" font-size="11">{{.Max}}</text>
+<text x="{{.Pad}}" y="{{.Height}}" font-size="11" dy="-24">0s</text>
+<text x="{{.Right}}" y="{{.Height}}" font-size="11" dy="-24" text-anchor="end">{{.End}}</text>
+{{range .Series}}<polyline fill="none" stroke="{{.Color}}" stroke-width="1.5" points="{{.Points}}"/>
+{{end}}</svg>
+<div class="legend">{{range .Series}}<span style="color: {{.Color}}">&#9632; {{.Name}}</span>{{end}}</div>
+{{end}}
+</body>
+</html>
+`))
+
+// WriteHTML renders a self-contained HTML page with the report summary and
+// charts of its time series.
+func WriteHTML(w io.Writer, report Report) error {
+	return htmlReport.Execute(w, struct {
+		Report Report
+		Charts []chart
+	}{report, reportCharts(report.TimeSeries)})
+}
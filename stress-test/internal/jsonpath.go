@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// DecodeJSON decodes a response body keeping numbers as json.Number, so ids
+// render in templates exactly as the server sent them.
+func DecodeJSON(body []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// LookupJSON resolves a dotted path such as "data.id", "$.items[0].id" or
+// "items.0.id" in a decoded JSON document.
+func LookupJSON(v any, path string) (any, bool) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	path = strings.ReplaceAll(strings.ReplaceAll(path, "[", "."), "]", "")
+	if path == "" {
+		return v, true
+	}
+
+	for _, segment := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			child, ok := node[segment]
+			if !ok {
+				return nil, false
+			}
+			v = child
+		case []any:
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			v = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
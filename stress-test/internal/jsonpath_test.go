@@ -0,0 +1,39 @@
+package internal
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLookupJSON(t *testing.T) {
+	doc, err := DecodeJSON([]byte(`{"data":{"id":9007199254740993,"items":[{"id":"a"},{"id":"b"}],"empty":null},"ok":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		path  string
+		want  string
+		found bool
+	}{
+		{"data.id", "9007199254740993", true},
+		{"$.data.items[1].id", "b", true},
+		{"data.items.0.id", "a", true},
+		{"$.ok", "true", true},
+		{"data.empty", "<nil>", true},
+		{"data.items[2].id", "", false},
+		{"data.items[-1]", "", false},
+		{"data.items.x", "", false},
+		{"data.id.value", "", false},
+		{"missing", "", false},
+	}
+
+	for _, tc := range cases {
+		got, ok := LookupJSON(doc, tc.path)
+		if ok != tc.found || ok && fmt.Sprint(got) != tc.want {
+			t.Errorf("%s: expected %q %v, got %v %v", tc.path, tc.want, tc.found, got, ok)
+		}
+	}
+	if got, ok := LookupJSON(doc, "$"); !ok || got == nil {
+		t.Error("Expected the root path to return the document")
+	}
+}
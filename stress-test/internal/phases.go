@@ -0,0 +1,141 @@
+package internal
+
+import (
+	"crypto/tls"
+	"encoding/csv"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Phases splits a request in DNS lookup, TCP connect, TLS handshake, server
+// processing (Wait, from the request being written to the first response
+// byte) and body transfer. TTFB runs from sending the request to the first
+// response byte. Connection phases are zero when a pooled connection was
+// reused.
+type Phases struct {
+	DNS      time.Duration
+	Connect  time.Duration
+	TLS      time.Duration
+	Wait     time.Duration
+	Transfer time.Duration
+	TTFB     time.Duration
+}
+
+var phaseNames = []string{"dns", "connect", "tls", "wait", "transfer", "ttfb"}
+
+func (p Phases) values() []time.Duration {
+	return []time.Duration{p.DNS, p.Connect, p.TLS, p.Wait, p.Transfer, p.TTFB}
+}
+
+type PhaseStats struct {
+	Phase string        `json:"Phase"`
+	Count int64         `json:"Count"`
+	Mean  time.Duration `json:"Mean"`
+	P50   time.Duration `json:"P50"`
+	P90   time.Duration `json:"P90"`
+	P95   time.Duration `json:"P95"`
+	P99   time.Duration `json:"P99"`
+	Max   time.Duration `json:"Max"`
+}
+
+func phaseStats(name string, h *Histogram) PhaseStats {
+	return PhaseStats{
+		Phase: name,
+		Count: h.Total,
+		Mean:  h.Mean(),
+		P50:   h.Percentile(50),
+		P90:   h.Percentile(90),
+		P95:   h.Percentile(95),
+		P99:   h.Percentile(99),
+		Max:   h.Max,
+	}
+}
+
+// phaseTrace collects the timestamps of one request. The hooks may fire from
+// dialing goroutines, hence the lock.
+type phaseTrace struct {
+	mu sync.Mutex
+
+	sent         time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	wrote        time.Time
+	firstByte    time.Time
+	connected    bool
+	reused       bool
+}
+
+func (p *phaseTrace) at(t *time.Time) {
+	p.mu.Lock()
+	if t.IsZero() {
+		*t = time.Now()
+	}
+	p.mu.Unlock()
+}
+
+func (p *phaseTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { p.at(&p.dnsStart) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { p.at(&p.dnsDone) },
+		ConnectStart:      func(string, string) { p.at(&p.connectStart) },
+		ConnectDone:       func(string, string, error) { p.at(&p.connectDone) },
+		TLSHandshakeStart: func() { p.at(&p.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { p.at(&p.tlsDone) },
+		WroteRequest:      func(httptrace.WroteRequestInfo) { p.at(&p.wrote) },
+		GotFirstResponseByte: func() {
+			p.at(&p.firstByte)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			p.mu.Lock()
+			p.connected, p.reused = true, info.Reused
+			p.mu.Unlock()
+		},
+	}
+}
+
+// phases computes the breakdown once the body has been read at end, or with
+// a zero end when the request failed.
+func (p *phaseTrace) phases(end time.Time) Phases {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	span := func(from, to time.Time) time.Duration {
+		if from.IsZero() || to.IsZero() || to.Before(from) {
+			return 0
+		}
+		return to.Sub(from)
+	}
+	return Phases{
+		DNS:      span(p.dnsStart, p.dnsDone),
+		Connect:  span(p.connectStart, p.connectDone),
+		TLS:      span(p.tlsStart, p.tlsDone),
+		Wait:     span(p.wrote, p.firstByte),
+		Transfer: span(p.firstByte, end),
+		TTFB:     span(p.sent, p.firstByte),
+	}
+}
+
+func writePhasesCSV(w *csv.Writer, phases []PhaseStats) {
+	if len(phases) == 0 {
+		return
+	}
+	w.Write(nil)
+	w.Write([]string{"Phase", "Count", "Mean", "P50", "P90", "P95", "P99", "Max"})
+	for _, p := range phases {
+		w.Write([]string{
+			p.Phase,
+			strconv.FormatInt(p.Count, 10),
+			p.Mean.String(),
+			p.P50.String(),
+			p.P90.String(),
+			p.P95.String(),
+			p.P99.String(),
+			p.Max.String(),
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPhasesAndConnectionReuse(t *testing.T) {
+	ms := time.Millisecond
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * ms)
+		w.Write([]byte("first"))
+		w.(http.Flusher).Flush()
+		time.Sleep(10 * ms)
+		w.Write([]byte("rest"))
+	}))
+	defer srv.Close()
+
+	client, err := NewHTTPClient(ClientOptions{KeepAlive: true, MaxIdleConns: 1, Insecure: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := NewRecorder()
+	for i := 0; i < 5; i++ {
+		rec.Record(get(t, client, srv.URL))
+	}
+	report := rec.Report(time.Second, false)
+
+	if report.Connections != (ConnectionStats{New: 1, Reused: 4}) {
+		t.Errorf("Expected one new connection reused four times, got %+v", report.Connections)
+	}
+	phases := make(map[string]PhaseStats)
+	for _, p := range report.Phases {
+		phases[p.Phase] = p
+	}
+	// Connection phases only happen once; dialing an IP needs no DNS lookup.
+	cases := []struct {
+		phase string
+		count int64
+		min   time.Duration
+	}{
+		{"dns", 0, 0},
+		{"connect", 1, 0},
+		{"tls", 1, 0},
+		{"wait", 5, 20 * ms},
+		{"transfer", 5, 10 * ms},
+		{"ttfb", 5, 20 * ms},
+	}
+	for _, tc := range cases {
+		p, ok := phases[tc.phase]
+		if tc.count == 0 {
+			if ok {
+				t.Errorf("Expected no %s phase, got %+v", tc.phase, p)
+			}
+			continue
+		}
+		if p.Count != tc.count || p.P50 < tc.min || p.Max < p.P50 || p.Mean <= 0 {
+			t.Errorf("Expected %d %s samples of at least %v, got %+v", tc.count, tc.phase, tc.min, p)
+		}
+	}
+	if phases["ttfb"].Mean < phases["wait"].Mean {
+		t.Errorf("Expected TTFB to include the wait, got ttfb %v wait %v", phases["ttfb"].Mean, phases["wait"].Mean)
+	}
+}
+
+func TestWritePhasesCSV(t *testing.T) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	writePhasesCSV(w, []PhaseStats{{Phase: "wait", Count: 2, Mean: 3 * time.Millisecond, P50: 3 * time.Millisecond, P90: 4 * time.Millisecond,
+		P95: 4 * time.Millisecond, P99: 4 * time.Millisecond, Max: 4 * time.Millisecond}})
+	w.Flush()
+
+	want := "\nPhase,Count,Mean,P50,P90,P95,P99,Max\nwait,2,3ms,3ms,4ms,4ms,4ms,4ms\n"
+	if buf.String() != want {
+		t.Errorf("Expected %q, got %q", want, buf.String())
+	}
+}
@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Snapshot summarizes one interval of a running test. P95 is computed over
+// the last RollingIntervals intervals to smooth out short spikes.
+type Snapshot struct {
+	Time      time.Time     `json:"Time"`
+	Elapsed   time.Duration `json:"Elapsed"`
+	Requests  int64         `json:"Requests"`
+	Interval  int64         `json:"Interval"`
+	RPS       float64       `json:"RPS"`
+	P95       time.Duration `json:"P95"`
+	ErrorRate float64       `json:"ErrorRate"`
+	Final     bool          `json:"Final,omitempty"`
+}
+
+const RollingIntervals = 10
+
+// Snapshot closes the current interval and starts a new one.
+func (r *Recorder) Snapshot(now time.Time) Snapshot {
+	window := NewHistogram()
+	r.recent = append(r.recent, r.interval)
+	if len(r.recent) > RollingIntervals {
+		r.recent = r.recent[1:]
+	}
+	for _, h := range r.recent {
+		window.Merge(h)
+	}
+
+	s := Snapshot{
+		Time:     now,
+		Elapsed:  now.Sub(r.start),
+		Requests: int64(r.Requests()),
+		Interval: r.interval.Total + int64(r.unsent),
+		P95:      window.Percentile(95),
+	}
+	if d := now.Sub(r.last).Seconds(); d > 0 {
+		s.RPS = float64(s.Interval) / d
+	}
+	if s.Interval > 0 {
+		s.ErrorRate = float64(r.failed) / float64(s.Interval)
+	}
+
+	r.series = append(r.series, intervalStats(s.Elapsed, s.Interval, s.RPS, r.interval, r.codes, r.failed))
+
+	r.last = now
+	r.interval = NewHistogram()
+	r.codes = make(map[int]int)
+	r.failed = 0
+	r.unsent = 0
+	return s
+}
+
+// Collect records results until the channel is closed, passing a snapshot
+// to every sink each interval and a final one at the end.
+func Collect(results <-chan Result, rec *Recorder, interval time.Duration, sinks ...func(Snapshot)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	emit := func(s Snapshot) {
+		for _, sink := range sinks {
+			sink(s)
+		}
+	}
+	for {
+		select {
+		case res, ok := <-results:
+			if !ok {
+				s := rec.Snapshot(time.Now())
+				s.Final = true
+				emit(s)
+				return
+			}
+			rec.Record(res)
+		case now := <-ticker.C:
+			emit(rec.Snapshot(now))
+		}
+	}
+}
+
+// PrintProgress redraws a one-line status on a terminal.
+func PrintProgress(w io.Writer) func(Snapshot) {
+	return func(s Snapshot) {
+		fmt.Fprintf(w, "\r\033[K[%v] %d requests | %.1f rps | p95 %v | errors %.2f%%",
+			s.Elapsed.Round(time.Second), s.Requests, s.RPS, s.P95.Round(time.Microsecond), s.ErrorRate*100)
+		if s.Final {
+			fmt.Fprintln(w)
+		}
+	}
+}
+
+// StreamJSON writes every snapshot as a JSON line, for dashboards tailing
+// the file.
+func StreamJSON(w io.Writer) func(Snapshot) {
+	enc := json.NewEncoder(w)
+	return func(s Snapshot) {
+		enc.Encode(s)
+	}
+}
+
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestPrintProgress(t *testing.T) {
+	cases := []struct {
+		name string
+		s    Snapshot
+		want string
+	}{
+		{"running", Snapshot{Elapsed: 1499 * time.Millisecond, Requests: 120, RPS: 80.04, P95: 12345678 * time.Nanosecond, ErrorRate: 0.025},
+			"\r\033[K[1s] 120 requests | 80.0 rps | p95 12.346ms | errors 2.50%"},
+		{"final", Snapshot{Elapsed: 10 * time.Second, Requests: 1000, RPS: 100, P95: 3 * time.Millisecond, Final: true},
+			"\r\033[K[10s] 1000 requests | 100.0 rps | p95 3ms | errors 0.00%\n"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			PrintProgress(&buf)(tc.s)
+			if buf.String() != tc.want {
+				t.Errorf("Expected %q, got %q", tc.want, buf.String())
+			}
+		})
+	}
+}
+
+func TestStreamJSON(t *testing.T) {
+	var buf bytes.Buffer
+	sink := StreamJSON(&buf)
+	sink(Snapshot{Requests: 1})
+	sink(Snapshot{Requests: 2, Final: true})
+
+	dec := json.NewDecoder(&buf)
+	for _, want := range []Snapshot{{Requests: 1}, {Requests: 2, Final: true}} {
+		var got Snapshot
+		if err := dec.Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("Expected %+v, got %+v", want, got)
+		}
+	}
+}
+
+func TestCollectStopsWithFinalSnapshot(t *testing.T) {
+	results := make(chan Result)
+	rec := NewRecorder()
+	var snapshots []Snapshot
+	done := make(chan struct{})
+	go func() {
+		Collect(results, rec, time.Hour, func(s Snapshot) { snapshots = append(snapshots, s) })
+		close(done)
+	}()
+
+	for i := 0; i < 3; i++ {
+		results <- Result{StatusCode: 200, Duration: time.Millisecond}
+	}
+	results <- Result{StatusCode: 500, Duration: time.Millisecond}
+	close(results)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected Collect to return once results are closed")
+	}
+
+	if len(snapshots) != 1 || !snapshots[0].Final {
+		t.Fatalf("Expected a single final snapshot, got %+v", snapshots)
+	}
+	if s := snapshots[0]; s.Requests != 4 || s.Interval != 4 || s.ErrorRate != 0.25 {
+		t.Errorf("Expected every result in the final snapshot, got %+v", s)
+	}
+	if rec.Report(time.Second, false).TotalRequests != 4 {
+		t.Errorf("Expected the recorder to hold every result")
+	}
+}
+
+func TestCollectEmitsEveryInterval(t *testing.T) {
+	results := make(chan Result)
+	var count int
+	ticked := make(chan struct{}, 1)
+	done := make(chan struct{})
+	go func() {
+		Collect(results, NewRecorder(), time.Millisecond, func(s Snapshot) {
+			count++
+			if !s.Final {
+				select {
+				case ticked <- struct{}{}:
+				default:
+				}
+			}
+		})
+		close(done)
+	}()
+
+	select {
+	case <-ticked:
+	case <-time.After(time.Second):
+		t.Fatal("Expected a snapshot every interval")
+	}
+	close(results)
+	<-done
+	if count < 2 {
+		t.Errorf("Expected interval snapshots followed by the final one, got %d", count)
+	}
+}
@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"strconv"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Protocol decides how status codes are read: HTTP status codes, or gRPC
+// status codes when load testing a gRPC method.
+type Protocol string
+
+const (
+	ProtocolHTTP    Protocol = "http"
+	ProtocolGRPC    Protocol = "grpc"
+	ProtocolGraphQL Protocol = "graphql"
+)
+
+func (p Protocol) Success(res Result) bool {
+	if res.Assertion != "" {
+		return false
+	}
+	if p == ProtocolGRPC {
+		return res.StatusCode == int(codes.OK)
+	}
+	if res.StatusChecked {
+		return res.GraphQLErrors == 0
+	}
+	return res.StatusCode == 200 && res.GraphQLErrors == 0
+}
+
+// Failed reports transport errors, 4xx/5xx responses, GraphQL errors and
+// failed assertions, or any non-OK gRPC status.
+func (p Protocol) Failed(res Result) bool {
+	if res.Assertion != "" {
+		return true
+	}
+	if p == ProtocolGRPC {
+		return res.StatusCode != int(codes.OK)
+	}
+	if res.StatusChecked {
+		return res.GraphQLErrors > 0
+	}
+	return res.StatusCode < 200 || res.StatusCode >= 400 || res.GraphQLErrors > 0
+}
+
+// SuccessName is the status counted as successful.
+func (p Protocol) SuccessName() string {
+	if p == ProtocolGRPC {
+		return codes.OK.String()
+	}
+	return "200"
+}
+
+func (p Protocol) StatusName(code int) string {
+	if p == ProtocolGRPC && code >= 0 {
+		return codes.Code(code).String()
+	}
+	return strconv.Itoa(code)
+}
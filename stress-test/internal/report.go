@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -18,61 +17,225 @@ type Bucket struct {
 }
 
 type Report struct {
+	Protocol      Protocol        `json:"Protocol,omitempty"`
+	Accepted      string          `json:"Accepted,omitempty"`
 	TotalRequests int             `json:"TotalRequests"`
 	SuccessCount  int             `json:"SuccessCount"`
 	StatusCodes   map[int]int     `json:"StatusCodes"`
 	Duration      time.Duration   `json:"Duration"`
-	Latencies     []time.Duration `json:"Latencies,omitempty"`
+	RPS           float64         `json:"RPS"`
+	ErrorRate     float64         `json:"ErrorRate"`
 	Min           time.Duration   `json:"Min"`
 	Max           time.Duration   `json:"Max"`
 	Mean          time.Duration   `json:"Mean"`
+	P50           time.Duration   `json:"P50"`
+	P75           time.Duration   `json:"P75"`
+	P90           time.Duration   `json:"P90"`
 	P95           time.Duration   `json:"P95"`
 	P99           time.Duration   `json:"P99"`
+	P999          time.Duration   `json:"P999"`
+	FailedCount   int             `json:"FailedCount"`
+	Connections   ConnectionStats `json:"Connections"`
+	Phases        []PhaseStats    `json:"Phases,omitempty"`
+	GraphQL       *GraphQLStats   `json:"GraphQL,omitempty"`
 	Histogram     []Bucket        `json:"Histogram,omitempty"`
+
+	Errors map[ErrorCategory]*ErrorSummary `json:"Errors,omitempty"`
+	// AssertionFailures counts responses failing an assertion, keyed in
+	// Assertions by the first assertion they failed.
+	AssertionFailures int               `json:"AssertionFailures,omitempty"`
+	Assertions        map[string]int    `json:"Assertions,omitempty"`
+	TimeSeries        []IntervalStats   `json:"TimeSeries,omitempty"`
+	Thresholds        []ThresholdResult `json:"Thresholds,omitempty"`
+}
+
+// Recorder aggregates results as they arrive, so memory does not grow with
+// the number of requests.
+type Recorder struct {
+	Protocol Protocol
+	// Accepted lists the status ranges counted as successful when they
+	// replace the protocol's default.
+	Accepted     string
+	StatusCodes  map[int]int
+	SuccessCount int
+	Latency      *Histogram
+	FailedCount  int
+	Connections  ConnectionStats
+	GraphQL      GraphQLStats
+	Assertions   map[string]int
+	Phases       []*Histogram
+	Errors       map[ErrorCategory]*ErrorSummary
+	// Unsent counts requests that were never sent. They are counted as
+	// failed requests but kept out of the latency histograms.
+	Unsent int
+
+	start    time.Time
+	last     time.Time
+	interval *Histogram
+	codes    map[int]int
+	failed   int
+	unsent   int
+	recent   []*Histogram
+	series   []IntervalStats
 }
 
-func CalculateReport(results []Result, totalDuration time.Duration, detailed bool) Report {
-	statusCodes := make(map[int]int)
-	var latencies []time.Duration
-	var successCount int
+func NewRecorder() *Recorder {
+	now := time.Now()
+	phases := make([]*Histogram, len(phaseNames))
+	for i := range phases {
+		phases[i] = NewHistogram()
+	}
+	return &Recorder{
+		Protocol:    ProtocolHTTP,
+		Phases:      phases,
+		StatusCodes: make(map[int]int),
+		Assertions:  make(map[string]int),
+		Latency:     NewHistogram(),
+		Errors:      make(map[ErrorCategory]*ErrorSummary),
+		start:       now,
+		last:        now,
+		interval:    NewHistogram(),
+		codes:       make(map[int]int),
+	}
+}
 
-	for _, r := range results {
-		statusCodes[r.StatusCode]++
-		if r.StatusCode == 200 {
-			successCount++
+func (r *Recorder) Record(res Result) {
+	r.StatusCodes[res.StatusCode]++
+	if r.Protocol.Success(res) {
+		r.SuccessCount++
+	}
+	if res.Unsent {
+		r.Unsent++
+		r.unsent++
+	} else {
+		r.Latency.Record(res.Duration)
+		r.interval.Record(res.Duration)
+	}
+	r.codes[res.StatusCode]++
+	for i, d := range res.Phases.values() {
+		if d > 0 {
+			r.Phases[i].Record(d)
+		}
+	}
+	r.GraphQL.record(res)
+	if res.Assertion != "" {
+		r.Assertions[res.Assertion]++
+	}
+	if res.Reused {
+		r.Connections.Reused++
+	} else if res.Connected {
+		r.Connections.New++
+	}
+	if r.Protocol.Failed(res) {
+		r.FailedCount++
+		r.failed++
+	}
+	if res.Error != "" {
+		summary, ok := r.Errors[res.Error]
+		if !ok {
+			summary = &ErrorSummary{Sample: res.ErrorMessage}
+			r.Errors[res.Error] = summary
 		}
-		latencies = append(latencies, r.Duration)
+		summary.Count++
 	}
+}
 
-	sort.Slice(latencies, func(i, j int) bool {
-		return latencies[i] < latencies[j]
-	})
+// SuccessName is the status counted as successful.
+func (r Report) SuccessName() string {
+	if r.Accepted != "" {
+		return r.Accepted
+	}
+	return r.Protocol.SuccessName()
+}
 
-	min := latencies[0]
-	max := latencies[len(latencies)-1]
-	mean := time.Duration(0)
-	for _, l := range latencies {
-		mean += l
+// Merge adds what another recorder collected, such as an agent's update in
+// a distributed run, counting it in the current interval.
+func (r *Recorder) Merge(other *Recorder) {
+	for code, count := range other.StatusCodes {
+		r.StatusCodes[code] += count
+		r.codes[code] += count
+	}
+	r.SuccessCount += other.SuccessCount
+	r.Latency.Merge(other.Latency)
+	r.interval.Merge(other.Latency)
+	r.Unsent += other.Unsent
+	r.unsent += other.Unsent
+	r.FailedCount += other.FailedCount
+	r.failed += other.FailedCount
+	r.Connections.New += other.Connections.New
+	r.Connections.Reused += other.Connections.Reused
+	r.GraphQL.merge(other.GraphQL)
+	for name, count := range other.Assertions {
+		r.Assertions[name] += count
+	}
+	for i := range r.Phases {
+		if i < len(other.Phases) {
+			r.Phases[i].Merge(other.Phases[i])
+		}
+	}
+	for category, summary := range other.Errors {
+		existing, ok := r.Errors[category]
+		if !ok {
+			existing = &ErrorSummary{Sample: summary.Sample}
+			r.Errors[category] = existing
+		}
+		existing.Count += summary.Count
 	}
-	mean /= time.Duration(len(latencies))
+}
 
-	p95 := latencies[int(float64(len(latencies))*0.95)-1]
-	p99 := latencies[int(float64(len(latencies))*0.99)-1]
+// Requests counts every request recorded, sent or not.
+func (r *Recorder) Requests() int {
+	return int(r.Latency.Total) + r.Unsent
+}
 
+func (r *Recorder) Report(totalDuration time.Duration, detailed bool) Report {
+	h := r.Latency
 	report := Report{
-		TotalRequests: len(results),
-		SuccessCount:  successCount,
-		StatusCodes:   statusCodes,
+		Protocol:      r.Protocol,
+		Accepted:      r.Accepted,
+		TotalRequests: r.Requests(),
+		SuccessCount:  r.SuccessCount,
+		StatusCodes:   r.StatusCodes,
 		Duration:      totalDuration,
-		Min:           min,
-		Max:           max,
-		Mean:          mean,
-		P95:           p95,
-		P99:           p99,
+		Min:           h.Min,
+		Max:           h.Max,
+		Mean:          h.Mean(),
+		P50:           h.Percentile(50),
+		P75:           h.Percentile(75),
+		P90:           h.Percentile(90),
+		P95:           h.Percentile(95),
+		P99:           h.Percentile(99),
+		P999:          h.Percentile(99.9),
+		FailedCount:   r.FailedCount,
+		Connections:   r.Connections,
+		TimeSeries:    r.series,
+	}
+	for i, name := range phaseNames {
+		if r.Phases[i].Total > 0 {
+			report.Phases = append(report.Phases, phaseStats(name, r.Phases[i]))
+		}
+	}
+	if r.Protocol == ProtocolGraphQL {
+		report.GraphQL = &r.GraphQL
+	}
+	for _, count := range r.Assertions {
+		report.AssertionFailures += count
+	}
+	if report.AssertionFailures > 0 {
+		report.Assertions = r.Assertions
+	}
+	if totalDuration > 0 {
+		report.RPS = float64(report.TotalRequests) / totalDuration.Seconds()
+	}
+	if report.TotalRequests > 0 {
+		report.ErrorRate = float64(r.FailedCount) / float64(report.TotalRequests)
+	}
+	if len(r.Errors) > 0 {
+		report.Errors = r.Errors
 	}
 
 	if detailed {
-		report.Latencies = latencies
+		report.Histogram, _ = BuildHistogram(h)
 	}
 
 	return report
@@ -81,48 +244,123 @@ func CalculateReport(results []Result, totalDuration time.Duration, detailed boo
 func PrintReport(report Report, output string) {
 	switch strings.ToLower(output) {
 	case "json":
-		data, _ := json.MarshalIndent(report, "", "  ")
-		fmt.Println(string(data))
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
 
 	case "csv":
 		writer := csv.NewWriter(os.Stdout)
 		defer writer.Flush()
 		writer.Write([]string{
-			"TotalRequests", "Successful", "Duration", "Min", "Max", "Mean", "P95", "P99", "StatusCodes",
+			"TotalRequests", "Successful", "Duration", "RPS", "ErrorRate", "Min", "Max", "Mean", "P50", "P75", "P90", "P95", "P99", "P99.9", "Failed", "NewConnections", "ReusedConnections", "StatusCodes", "Errors",
 		})
-		var statusCodes []string
-		for code, count := range report.StatusCodes {
-			statusCodes = append(statusCodes, fmt.Sprintf("%d:%d", code, count))
+		var errs []string
+		for category, summary := range report.Errors {
+			errs = append(errs, fmt.Sprintf("%s:%d", category, summary.Count))
 		}
 		writer.Write([]string{
 			strconv.Itoa(report.TotalRequests),
 			strconv.Itoa(report.SuccessCount),
 			report.Duration.String(),
+			strconv.FormatFloat(report.RPS, 'f', 2, 64),
+			strconv.FormatFloat(report.ErrorRate, 'f', 4, 64),
 			report.Min.String(),
 			report.Max.String(),
 			report.Mean.String(),
+			report.P50.String(),
+			report.P75.String(),
+			report.P90.String(),
 			report.P95.String(),
 			report.P99.String(),
-			strings.Join(statusCodes, " "),
+			report.P999.String(),
+			strconv.Itoa(report.FailedCount),
+			strconv.Itoa(report.Connections.New),
+			strconv.Itoa(report.Connections.Reused),
+			formatCodes(report.StatusCodes),
+			strings.Join(errs, " "),
 		})
+		writeGraphQLCSV(writer, report.GraphQL)
+		writeAssertionsCSV(writer, report.Assertions)
+		writePhasesCSV(writer, report.Phases)
+		writeSeriesCSV(writer, report.TimeSeries)
 
 	default:
 		fmt.Println("===== Stress Test Report =====")
 		fmt.Printf("Total Requests: %d\n", report.TotalRequests)
-		fmt.Printf("Successful (200): %d\n", report.SuccessCount)
-		fmt.Printf("Duration: %v\n\n", report.Duration)
+		fmt.Printf("Successful (%s): %d\n", report.SuccessName(), report.SuccessCount)
+		fmt.Printf("Failed: %d\n", report.FailedCount)
+		fmt.Printf("Duration: %v\n", report.Duration)
+		fmt.Printf("Requests/sec: %.2f\n", report.RPS)
+		fmt.Printf("Error rate: %.2f%%\n\n", report.ErrorRate*100)
 
 		fmt.Println("Latency Metrics:")
 		fmt.Printf("  Min:   %v\n", report.Min)
 		fmt.Printf("  Max:   %v\n", report.Max)
 		fmt.Printf("  Mean:  %v\n", report.Mean)
+		fmt.Printf("  P50:   %v\n", report.P50)
+		fmt.Printf("  P75:   %v\n", report.P75)
+		fmt.Printf("  P90:   %v\n", report.P90)
 		fmt.Printf("  P95:   %v\n", report.P95)
 		fmt.Printf("  P99:   %v\n", report.P99)
+		fmt.Printf("  P99.9: %v\n", report.P999)
+		fmt.Println()
+
+		if len(report.Phases) > 0 {
+			fmt.Println("Phase Timings:")
+			fmt.Printf("  %-9s %8s %12s %12s %12s %12s %12s\n", "Phase", "Count", "Mean", "P50", "P95", "P99", "Max")
+			for _, p := range report.Phases {
+				fmt.Printf("  %-9s %8d %12v %12v %12v %12v %12v\n", p.Phase, p.Count, p.Mean, p.P50, p.P95, p.P99, p.Max)
+			}
+			fmt.Println()
+		}
+
+		fmt.Println("Connections:")
+		fmt.Printf("  New:    %d\n", report.Connections.New)
+		fmt.Printf("  Reused: %d (%.1f%%)\n", report.Connections.Reused, report.Connections.ReuseRate()*100)
 		fmt.Println()
 
 		fmt.Println("Status Codes Distribution:")
 		for code, count := range report.StatusCodes {
-			fmt.Printf("  %d : %d\n", code, count)
+			fmt.Printf("  %s : %d\n", report.Protocol.StatusName(code), count)
+		}
+
+		if report.GraphQL != nil {
+			fmt.Println()
+			fmt.Println("GraphQL Errors:")
+			fmt.Printf("  Responses with errors: %d\n", report.GraphQL.Responses)
+			fmt.Printf("  Total errors:          %d\n", report.GraphQL.Errors)
+			for msg, count := range report.GraphQL.Messages {
+				fmt.Printf("  %s : %d\n", msg, count)
+			}
+		}
+
+		if report.AssertionFailures > 0 {
+			fmt.Println()
+			fmt.Printf("Assertion Failures: %d\n", report.AssertionFailures)
+			for name, count := range report.Assertions {
+				fmt.Printf("  %s : %d\n", name, count)
+			}
+		}
+
+		if len(report.Errors) > 0 {
+			fmt.Println()
+			fmt.Println("Errors:")
+			for category, summary := range report.Errors {
+				fmt.Printf("  %s : %d (%s)\n", category, summary.Count, summary.Sample)
+			}
+		}
+
+		if len(report.Thresholds) > 0 {
+			fmt.Println()
+			fmt.Println("Thresholds:")
+			for _, t := range report.Thresholds {
+				status := "PASS"
+				if !t.Passed {
+					status = "FAIL"
+				}
+				fmt.Printf("  %s %s (actual %s)\n", status, t.Expr, t.Actual)
+			}
 		}
 
 		if len(report.Histogram) > 0 {
@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRecorderMerge(t *testing.T) {
+	ms := time.Millisecond
+	results := []Result{
+		{StatusCode: 200, Duration: 3 * ms, Connected: true},
+		{StatusCode: 200, Duration: 5 * ms, Reused: true},
+		{StatusCode: 500, Duration: 8 * ms, Reused: true},
+		{StatusCode: 200, Duration: 4 * ms, Assertion: "body contains \"ok\""},
+		{StatusCode: -1, Duration: 30 * ms, Error: ErrorTimeout, ErrorMessage: "deadline exceeded"},
+		{StatusCode: -1, Duration: 31 * ms, Error: ErrorTimeout, ErrorMessage: "deadline exceeded"},
+		unsentResult(errors.New("bad url")),
+	}
+
+	all, agent, coordinator := NewRecorder(), NewRecorder(), NewRecorder()
+	for i, res := range results {
+		all.Record(res)
+		if i%2 == 0 {
+			agent.Record(res)
+		} else {
+			coordinator.Record(res)
+		}
+	}
+	// Agents send their recorder as JSON.
+	data, err := json.Marshal(agent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var received Recorder
+	if err := json.Unmarshal(data, &received); err != nil {
+		t.Fatal(err)
+	}
+	coordinator.Merge(&received)
+
+	want, got := all.Report(time.Second, false), coordinator.Report(time.Second, false)
+	if got.TotalRequests != want.TotalRequests || got.SuccessCount != want.SuccessCount || got.FailedCount != want.FailedCount {
+		t.Errorf("Expected counts %d/%d/%d, got %d/%d/%d", want.TotalRequests, want.SuccessCount, want.FailedCount,
+			got.TotalRequests, got.SuccessCount, got.FailedCount)
+	}
+	if got.Min != want.Min || got.Max != want.Max || got.P50 != want.P50 || got.P99 != want.P99 {
+		t.Errorf("Expected latencies %v/%v/%v/%v, got %v/%v/%v/%v", want.Min, want.P50, want.P99, want.Max,
+			got.Min, got.P50, got.P99, got.Max)
+	}
+	if got.StatusCodes[200] != 3 || got.StatusCodes[500] != 1 || got.StatusCodes[-1] != 3 {
+		t.Errorf("Expected merged status codes, got %v", got.StatusCodes)
+	}
+	if coordinator.Errors[ErrorTimeout].Count != 2 || coordinator.Assertions["body contains \"ok\""] != 1 {
+		t.Errorf("Expected merged errors and assertions, got %v %v", coordinator.Errors, coordinator.Assertions)
+	}
+	if coordinator.Unsent != 1 {
+		t.Errorf("Expected the unsent request merged, got %d", coordinator.Unsent)
+	}
+	if coordinator.Connections != all.Connections {
+		t.Errorf("Expected connections %+v, got %+v", all.Connections, coordinator.Connections)
+	}
+}
+
+func TestRecorderKeepsUnsentOutOfLatency(t *testing.T) {
+	ms := time.Millisecond
+	rec := NewRecorder()
+	rec.Record(Result{StatusCode: 200, Duration: 10 * ms})
+	rec.Record(Result{StatusCode: 200, Duration: 20 * ms})
+	rec.Record(unsentResult(errors.New(`template: url: map has no entry for key "id"`)))
+
+	s := rec.Snapshot(time.Now())
+	if s.Requests != 3 || s.Interval != 3 || s.ErrorRate != 1.0/3 {
+		t.Errorf("Expected the unsent request counted in the snapshot, got %+v", s)
+	}
+	report := rec.Report(time.Second, false)
+	if report.TotalRequests != 3 || report.FailedCount != 1 || report.Errors[ErrorInvalidRequest].Count != 1 {
+		t.Errorf("Expected the unsent request counted as a failed request, got %+v", report)
+	}
+	if report.Min != 10*ms || report.Mean != 15*ms {
+		t.Errorf("Expected latencies from sent requests only, got min %v mean %v", report.Min, report.Mean)
+	}
+	if series := report.TimeSeries; len(series) != 1 || series[0].Requests != 3 || series[0].P50 < 10*ms {
+		t.Errorf("Expected the interval to count 3 requests with sent latencies, got %+v", series)
+	}
+}
@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+type RequestSpec struct {
+	Method  string
+	URL     string
+	Headers http.Header
+	Body    []byte
+	// Assert, when set, is checked against every response.
+	Assert *Assertions
+}
+
+func (s RequestSpec) NewRequest() (*http.Request, error) {
+	req, err := http.NewRequest(s.Method, s.URL, bytes.NewReader(s.Body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range s.Headers {
+		for _, v := range values {
+			req.Header.Add(name, v)
+		}
+	}
+	if host := s.Headers.Get("Host"); host != "" {
+		req.Host = host
+	}
+	return req, nil
+}
+
+// HeaderFlags collects repeated -H "Name: value" flags.
+type HeaderFlags []string
+
+func (h *HeaderFlags) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *HeaderFlags) Set(value string) error {
+	if _, _, ok := strings.Cut(value, ":"); !ok {
+		return fmt.Errorf("invalid header %q, expected \"Name: value\"", value)
+	}
+	*h = append(*h, value)
+	return nil
+}
+
+func (h HeaderFlags) Header() http.Header {
+	header := make(http.Header)
+	for _, raw := range h {
+		name, value, _ := strings.Cut(raw, ":")
+		header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	return header
+}
+
+// ListFlags collects a repeatable string flag, also splitting on commas.
+type ListFlags []string
+
+func (l *ListFlags) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *ListFlags) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// LoadBody returns the request body from either an inline string or a file,
+// where a file path may also be given curl-style as "@path".
+func LoadBody(body, file string) ([]byte, error) {
+	if body != "" && file != "" {
+		return nil, fmt.Errorf("use either --body or --body-file, not both")
+	}
+	if strings.HasPrefix(body, "@") {
+		file = body[1:]
+	}
+	if file != "" {
+		return os.ReadFile(file)
+	}
+	return []byte(body), nil
+}
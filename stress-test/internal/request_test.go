@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHeaderFlags(t *testing.T) {
+	var headers HeaderFlags
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Var(&headers, "H", "")
+	err := fs.Parse([]string{"-H", "Accept: text/plain", "-H", "X-Trace:1", "-H", "Accept: application/json", "-H", "Host: api.example.test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spec := RequestSpec{Method: "GET", URL: "http://127.0.0.1/", Headers: headers.Header()}
+	req, err := spec.NewRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := req.Header.Values("Accept"); !reflect.DeepEqual(got, []string{"text/plain", "application/json"}) {
+		t.Errorf("Expected both Accept values in order, got %q", got)
+	}
+	if got := req.Header.Get("X-Trace"); got != "1" {
+		t.Errorf("Expected X-Trace 1, got %q", got)
+	}
+	if req.Host != "api.example.test" {
+		t.Errorf("Expected the Host header to set the request host, got %q", req.Host)
+	}
+
+	if err := fs.Parse([]string{"-H", "no-colon"}); err == nil {
+		t.Error("Expected an error for a header without a colon")
+	}
+}
+
+func TestLoadBody(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "body.json")
+	if err := os.WriteFile(file, []byte(`{"from":"file"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name       string
+		body, file string
+		want       string
+		wantErr    bool
+	}{
+		{"no body", "", "", "", false},
+		{"inline", `{"from":"flag"}`, "", `{"from":"flag"}`, false},
+		{"body file", "", file, `{"from":"file"}`, false},
+		{"curl style", "@" + file, "", `{"from":"file"}`, false},
+		{"both", `{"from":"flag"}`, file, "", true},
+		{"missing file", "", file + ".missing", "", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := LoadBody(tc.body, tc.file)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Expected error %v, got %v", tc.wantErr, err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("Expected body %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
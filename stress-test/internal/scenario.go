@@ -0,0 +1,255 @@
+package internal
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scenario is a session each virtual user runs per iteration: a flow is
+// picked by weight and its steps are executed in order. URLs, headers and
+// bodies are Go templates over the scenario vars, the values extracted by
+// earlier steps and the built-ins vu and iteration. Files may be YAML or
+// JSON; a top-level steps list is shorthand for a single flow.
+type Scenario struct {
+	Vars  map[string]string `yaml:"vars"`
+	Flows []Flow            `yaml:"flows"`
+	Steps []Step            `yaml:"steps"`
+	// Feeder, when set, supplies a data row to every iteration.
+	Feeder *Feeder `yaml:"-"`
+
+	baseURL string
+	weights int
+}
+
+type Flow struct {
+	Name   string `yaml:"name"`
+	Weight int    `yaml:"weight"`
+	Steps  []Step `yaml:"steps"`
+}
+
+type Step struct {
+	Name    string            `yaml:"name"`
+	Method  string            `yaml:"method"`
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body"`
+	Extract map[string]string `yaml:"extract"`
+	Think   time.Duration     `yaml:"think"`
+	Expect  *Assertions       `yaml:"expect"`
+
+	url     *template.Template
+	headers map[string]*template.Template
+	body    *template.Template
+}
+
+// LoadScenario reads a scenario file. Step URLs starting with "/" are
+// resolved against baseURL, and steps without an expect block are checked
+// against assert.
+func LoadScenario(path, baseURL string, assert *Assertions) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is valid YAML, so one decoder handles both formats.
+	var s Scenario
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parse scenario %s: %w", path, err)
+	}
+	if err := s.init(baseURL, assert); err != nil {
+		return nil, fmt.Errorf("scenario %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// NewScenario prepares a scenario built in code, such as a single templated
+// request fed by a Feeder.
+func NewScenario(s *Scenario, baseURL string, assert *Assertions) (*Scenario, error) {
+	if err := s.init(baseURL, assert); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Scenario) init(baseURL string, assert *Assertions) error {
+	if len(s.Flows) == 0 && len(s.Steps) > 0 {
+		s.Flows = []Flow{{Name: "default", Weight: 1, Steps: s.Steps}}
+	}
+	if len(s.Flows) == 0 {
+		return fmt.Errorf("no steps")
+	}
+
+	s.baseURL = strings.TrimSuffix(baseURL, "/")
+	for i := range s.Flows {
+		flow := &s.Flows[i]
+		if flow.Weight <= 0 {
+			flow.Weight = 1
+		}
+		s.weights += flow.Weight
+		if len(flow.Steps) == 0 {
+			return fmt.Errorf("flow %q has no steps", flow.Name)
+		}
+		for j := range flow.Steps {
+			if flow.Steps[j].Expect == nil {
+				flow.Steps[j].Expect = assert
+			}
+			if err := flow.Steps[j].compile(); err != nil {
+				return fmt.Errorf("flow %q step %d: %w", flow.Name, j+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (st *Step) compile() error {
+	if st.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+	if st.Method == "" {
+		st.Method = http.MethodGet
+	}
+	st.Method = strings.ToUpper(st.Method)
+
+	var err error
+	if st.url, err = parseTemplate("url", st.URL); err != nil {
+		return err
+	}
+	if st.body, err = parseTemplate("body", st.Body); err != nil {
+		return err
+	}
+	if st.Expect != nil {
+		if err := st.Expect.compile(); err != nil {
+			return err
+		}
+	}
+	st.headers = make(map[string]*template.Template, len(st.Headers))
+	for name, value := range st.Headers {
+		if st.headers[name], err = parseTemplate(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=error").Parse(text)
+}
+
+func render(t *template.Template, data map[string]any) (string, error) {
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func (s *Scenario) pick() *Flow {
+	n := rand.IntN(s.weights)
+	for i := range s.Flows {
+		if n < s.Flows[i].Weight {
+			return &s.Flows[i]
+		}
+		n -= s.Flows[i].Weight
+	}
+	return &s.Flows[len(s.Flows)-1]
+}
+
+func (s *Scenario) data(vu *VirtualUser) map[string]any {
+	data := make(map[string]any, len(s.Vars)+len(vu.Vars)+2)
+	for k, v := range s.Vars {
+		data[k] = v
+	}
+	for k, v := range vu.Vars {
+		data[k] = v
+	}
+	data["vu"] = vu.ID
+	data["iteration"] = vu.Iteration
+	return data
+}
+
+func (s *Scenario) request(st *Step, data map[string]any) (RequestSpec, error) {
+	url, err := render(st.url, data)
+	if err != nil {
+		return RequestSpec{}, err
+	}
+	if strings.HasPrefix(url, "/") {
+		url = s.baseURL + url
+	}
+	body, err := render(st.body, data)
+	if err != nil {
+		return RequestSpec{}, err
+	}
+	headers := make(http.Header, len(st.headers))
+	for name, t := range st.headers {
+		value, err := render(t, data)
+		if err != nil {
+			return RequestSpec{}, err
+		}
+		headers.Set(name, value)
+	}
+	return RequestSpec{Method: st.Method, URL: url, Headers: headers, Body: []byte(body), Assert: st.Expect}, nil
+}
+
+// Execute runs one flow. The first request is timed from start so open-model
+// queueing is accounted for; later ones from when they are sent. The flow
+// stops early when a request fails, an assertion fails or a value cannot be
+// extracted, since the following steps depend on it.
+func (s *Scenario) Execute(vu *VirtualUser, start time.Time, results chan<- Result) {
+	clear(vu.Vars)
+	if s.Feeder != nil {
+		row, ok := s.Feeder.Next(vu)
+		if !ok {
+			vu.Done = true
+			return
+		}
+		for k, v := range row {
+			vu.Vars[k] = v
+		}
+	}
+	flow := s.pick()
+	for i := range flow.Steps {
+		st := &flow.Steps[i]
+		if i > 0 {
+			start = time.Now()
+		}
+
+		spec, err := s.request(st, s.data(vu))
+		if err != nil {
+			results <- unsentResult(err)
+			return
+		}
+		res, body := do(vu.Client, spec, start, len(st.Extract) > 0)
+		results <- res
+		if res.StatusCode == -1 || res.Assertion != "" || !extract(st.Extract, body, vu.Vars) {
+			return
+		}
+
+		if st.Think > 0 {
+			time.Sleep(st.Think)
+		}
+	}
+}
+
+func extract(paths map[string]string, body []byte, vars map[string]any) bool {
+	if len(paths) == 0 {
+		return true
+	}
+	doc, err := DecodeJSON(body)
+	if err != nil {
+		return false
+	}
+	for name, path := range paths {
+		v, ok := LookupJSON(doc, path)
+		if !ok {
+			return false
+		}
+		vars[name] = v
+	}
+	return true
+}
@@ -0,0 +1,241 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// clinic mimics the Clean-Architecture patient and order endpoints. With
+// zeroIDs set, created records come back with id 0.
+type clinic struct {
+	zeroIDs bool
+
+	mu       sync.Mutex
+	nextID   int
+	requests []string
+	bodies   []string
+}
+
+func (c *clinic) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	c.mu.Lock()
+	c.requests = append(c.requests, r.Method+" "+r.URL.Path)
+	c.bodies = append(c.bodies, string(body))
+	c.nextID++
+	id := c.nextID
+	if c.zeroIDs {
+		id = 0
+	}
+	c.mu.Unlock()
+
+	status := http.StatusOK
+	switch {
+	case r.Method == http.MethodPost:
+		status = http.StatusCreated
+	case r.URL.Path == "/orders/0":
+		http.Error(w, `{"message":"Order not found"}`, http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"message":"ok","data":{"id":%d}}`, id)
+}
+
+func (c *clinic) seen() ([]string, []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.requests...), append([]string(nil), c.bodies...)
+}
+
+func execute(s *Scenario, vu *VirtualUser) []Result {
+	results := make(chan Result, 16)
+	s.Execute(vu, time.Now(), results)
+	close(results)
+	var all []Result
+	for res := range results {
+		all = append(all, res)
+	}
+	return all
+}
+
+func TestScenarioExtractsValues(t *testing.T) {
+	c := &clinic{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+	s, err := NewScenario(&Scenario{
+		Vars: map[string]string{"item": "Aspirin"},
+		Steps: []Step{
+			{Method: "post", URL: "/patients", Body: `{"name":"p{{.vu}}-{{.iteration}}"}`, Extract: map[string]string{"patient_id": "data.id"}},
+			{Method: "POST", URL: "/orders", Body: `{"item":"{{.item}}","patient_id":{{.patient_id}}}`, Extract: map[string]string{"order_id": "data.id"}},
+			{URL: "/orders/{{.order_id}}"},
+		},
+	}, srv.URL+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vu := NewVirtualUser(3, srv.Client())
+	vu.Iteration = 7
+	results := execute(s, vu)
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 requests, got %+v", results)
+	}
+	requests, bodies := c.seen()
+	want := []string{"POST /patients", "POST /orders", "GET /orders/2"}
+	for i := range want {
+		if requests[i] != want[i] {
+			t.Errorf("Expected request %d to be %q, got %q", i+1, want[i], requests[i])
+		}
+	}
+	if bodies[0] != `{"name":"p3-7"}` || bodies[1] != `{"item":"Aspirin","patient_id":1}` {
+		t.Errorf("Expected templated bodies, got %q", bodies)
+	}
+	if results[2].StatusCode != http.StatusOK {
+		t.Errorf("Expected the order fetched by its extracted id, got %d", results[2].StatusCode)
+	}
+}
+
+func TestScenarioStopsWhenExtractionFails(t *testing.T) {
+	c := &clinic{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+	s, err := NewScenario(&Scenario{Steps: []Step{
+		{URL: "/patients", Extract: map[string]string{"patient_id": "data.missing"}},
+		{URL: "/orders/{{.patient_id}}"},
+	}}, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if results := execute(s, NewVirtualUser(0, srv.Client())); len(results) != 1 {
+		t.Errorf("Expected the flow to stop after the failed extraction, got %+v", results)
+	}
+}
+
+func TestScenarioMissingKeyIsAnError(t *testing.T) {
+	c := &clinic{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+	s, err := NewScenario(&Scenario{Steps: []Step{
+		{URL: "/orders/{{.order_id}}"},
+		{URL: "/orders"},
+	}}, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := execute(s, NewVirtualUser(0, srv.Client()))
+	if len(results) != 1 || results[0].Error != ErrorInvalidRequest || !results[0].Unsent {
+		t.Fatalf("Expected one invalid request result, got %+v", results)
+	}
+	if requests, _ := c.seen(); len(requests) != 0 {
+		t.Errorf("Expected nothing sent for an unknown template key, got %q", requests)
+	}
+
+	if _, err := NewScenario(&Scenario{Steps: []Step{{URL: "/orders/{{.order_id"}}}, srv.URL, nil); err == nil {
+		t.Error("Expected an error for an invalid template")
+	}
+}
+
+func TestScenarioPicksFlowsByWeight(t *testing.T) {
+	s, err := NewScenario(&Scenario{Flows: []Flow{
+		{Name: "a", Weight: 6, Steps: []Step{{URL: "/a"}}},
+		{Name: "b", Weight: 3, Steps: []Step{{URL: "/b"}}},
+		{Name: "c", Steps: []Step{{URL: "/c"}}},
+	}}, "http://127.0.0.1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const draws = 20000
+	counts := make(map[string]int)
+	for i := 0; i < draws; i++ {
+		counts[s.pick().Name]++
+	}
+	for name, want := range map[string]float64{"a": 0.6, "b": 0.3, "c": 0.1} {
+		if got := float64(counts[name]) / draws; math.Abs(got-want) > 0.02 {
+			t.Errorf("Expected flow %s picked %.2f of the time, got %.3f", name, want, got)
+		}
+	}
+}
+
+func TestScenarioStepExpect(t *testing.T) {
+	c := &clinic{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+	defaults, err := NewAssertions(Assertions{Status: []string{"200"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewScenario(&Scenario{Steps: []Step{
+		{Method: "POST", URL: "/patients", Expect: &Assertions{Status: []string{"201"}, JSON: map[string]string{"data.id": "1"}}},
+		{Method: "POST", URL: "/orders"},
+		{URL: "/orders/1"},
+	}}, srv.URL, defaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := execute(s, NewVirtualUser(0, srv.Client()))
+	if len(results) != 2 {
+		t.Fatalf("Expected the flow to stop at the failed assertion, got %+v", results)
+	}
+	if results[0].Assertion != "" || !results[0].StatusChecked {
+		t.Errorf("Expected the step expect block to accept 201, got %+v", results[0])
+	}
+	if results[1].Assertion != "status 200" {
+		t.Errorf("Expected a step without expect to use the default assertions, got %+v", results[1])
+	}
+}
+
+func TestPatientOrderScenario(t *testing.T) {
+	cases := []struct {
+		name    string
+		zeroIDs bool
+		failed  string
+	}{
+		{"real ids", false, ""},
+		{"id 0", true, `matches /"id":[1-9]/`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(&clinic{zeroIDs: tc.zeroIDs})
+			defer srv.Close()
+			s, err := LoadScenario("../scenarios/patient-order.yaml", srv.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range s.Flows {
+				for j := range s.Flows[i].Steps {
+					s.Flows[i].Steps[j].Think = 0
+				}
+			}
+
+			vu := NewVirtualUser(0, srv.Client())
+			flows := 0
+			for ; vu.Iteration < 50; vu.Iteration++ {
+				results := execute(s, vu)
+				if len(results) == 1 && results[0].Assertion == "" {
+					continue // list-orders
+				}
+				flows++
+				last := results[len(results)-1]
+				if last.Assertion != tc.failed || !last.StatusChecked {
+					t.Fatalf("Expected the flow to end with assertion %q, got %+v", tc.failed, results)
+				}
+				if tc.failed == "" && len(results) != 3 {
+					t.Fatalf("Expected all three steps to run, got %+v", results)
+				}
+			}
+			if flows == 0 {
+				t.Error("Expected the patient-order flow to run")
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Stage ramps the request rate linearly from the previous target to Target
+// over Duration.
+type Stage struct {
+	Target   float64
+	Duration time.Duration
+}
+
+// ParseStages reads "rate:duration" entries separated by commas, e.g.
+// "500:60s,500:2m" ramps to 500 rps over a minute and then holds it for two.
+func ParseStages(raw string) ([]Stage, error) {
+	var stages []Stage
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		rate, duration, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid stage %q, expected rate:duration", entry)
+		}
+		target, err := strconv.ParseFloat(rate, 64)
+		if err != nil || target < 0 {
+			return nil, fmt.Errorf("invalid stage rate %q", rate)
+		}
+		d, err := time.ParseDuration(duration)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid stage duration %q", duration)
+		}
+		stages = append(stages, Stage{Target: target, Duration: d})
+	}
+	return stages, nil
+}
+
+// RateSchedule describes an open-model load: requests are issued at Start
+// rps, then follow the stages, and the last rate is held until Duration.
+type RateSchedule struct {
+	Start    float64
+	Stages   []Stage
+	Duration time.Duration
+}
+
+func (s RateSchedule) Total() time.Duration {
+	var total time.Duration
+	for _, stage := range s.Stages {
+		total += stage.Duration
+	}
+	if s.Duration > total {
+		return s.Duration
+	}
+	return total
+}
+
+// RateAt returns the target rate in requests per second at offset t.
+func (s RateSchedule) RateAt(t time.Duration) float64 {
+	rate := s.Start
+	var elapsed time.Duration
+	for _, stage := range s.Stages {
+		if t < elapsed+stage.Duration {
+			progress := float64(t-elapsed) / float64(stage.Duration)
+			return rate + (stage.Target-rate)*progress
+		}
+		rate = stage.Target
+		elapsed += stage.Duration
+	}
+	return rate
+}
+
+// At returns the offset at which request n (counting from 0) is sent: the
+// moment the integral of the rate reaches n, so request 0 goes out at t=0 and
+// ramps starting at zero rps send as soon as the area under the ramp allows.
+// It returns false when request n falls beyond the end of the schedule.
+func (s RateSchedule) At(n int) (time.Duration, bool) {
+	need := float64(n)
+	rate := s.Start
+	var elapsed time.Duration
+	for _, stage := range s.Stages {
+		secs := stage.Duration.Seconds()
+		area := (rate + stage.Target) / 2 * secs
+		if need <= area {
+			at := elapsed + seconds(rampTime(rate, (stage.Target-rate)/secs, need))
+			return at, at < s.Total()
+		}
+		need -= area
+		rate = stage.Target
+		elapsed += stage.Duration
+	}
+	if rate <= 0 {
+		return elapsed, false
+	}
+	at := elapsed + seconds(need/rate)
+	return at, at < s.Total()
+}
+
+// rampTime solves r0*t + slope*t^2/2 = area for t, in the form that stays
+// accurate when the slope is close to zero.
+func rampTime(r0, slope, area float64) float64 {
+	if area == 0 {
+		return 0
+	}
+	return 2 * area / (r0 + math.Sqrt(math.Max(r0*r0+2*slope*area, 0)))
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
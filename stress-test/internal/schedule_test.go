@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateScheduleAt(t *testing.T) {
+	ms := time.Millisecond
+	cases := []struct {
+		name     string
+		schedule RateSchedule
+		want     []time.Duration
+	}{
+		{
+			name:     "constant rate",
+			schedule: RateSchedule{Start: 4, Duration: time.Second},
+			want:     []time.Duration{0, 250 * ms, 500 * ms, 750 * ms},
+		},
+		{
+			// 10 rps/s ramp: n = 5t^2, t = sqrt(n/5).
+			name:     "ramp from zero",
+			schedule: RateSchedule{Stages: []Stage{{Target: 10, Duration: time.Second}}},
+			want:     []time.Duration{0, 447213595, 632455532, 774596669, 894427191},
+		},
+		{
+			// 2 requests in the ramp to 4 rps over 1s, then 4 rps held,
+			// then 4 - sqrt(16 - 2m) for the m-th while dropping to 0 over 4s;
+			// the 8th lands on the end of the schedule and is not sent.
+			name: "multi stage",
+			schedule: RateSchedule{Stages: []Stage{
+				{Target: 4, Duration: time.Second},
+				{Target: 4, Duration: time.Second},
+				{Target: 0, Duration: 4 * time.Second},
+			}},
+			want: []time.Duration{
+				0, 707106781, time.Second, 1250 * ms, 1500 * ms, 1750 * ms, 2 * time.Second,
+				2258342613, 2535898384, 2837722340, 3171572875, 3550510257, 4000 * ms, 4585786438,
+			},
+		},
+		{
+			name:     "zero rate sends nothing",
+			schedule: RateSchedule{Duration: time.Second},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []time.Duration
+			for n := 0; n < 100; n++ {
+				at, ok := tc.schedule.At(n)
+				if !ok {
+					break
+				}
+				got = append(got, at)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("Expected %d requests, got %d: %v", len(tc.want), len(got), got)
+			}
+			for i := range got {
+				if diff := got[i] - tc.want[i]; diff < -time.Microsecond || diff > time.Microsecond {
+					t.Errorf("Expected request %d at %v, got %v", i, tc.want[i], got[i])
+				}
+			}
+		})
+	}
+}
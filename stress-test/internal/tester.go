@@ -1,28 +1,152 @@
 package internal
 
 import (
+	"io"
 	"net/http"
+	"net/http/httptrace"
 	"sync"
 	"time"
 )
 
 type Result struct {
-	StatusCode int
-	Duration   time.Duration
+	StatusCode   int
+	Duration     time.Duration
+	Error        ErrorCategory
+	ErrorMessage string
+	// Connected is set once the request got a connection, and Reused when
+	// that connection came from the idle pool.
+	Connected bool
+	Reused    bool
+	Phases    Phases
+	// GraphQLErrors counts the entries of a GraphQL response errors array.
+	GraphQLErrors int
+	// Assertion is the first assertion the response failed. StatusChecked is
+	// set when an accepted status range replaced the default status check.
+	Assertion     string
+	StatusChecked bool
+	// Unsent marks a request that failed before it was sent, such as an
+	// invalid URL or template, so it has no latency.
+	Unsent bool
 }
 
-func Worker(url string, requests int, results chan<- Result, wg *sync.WaitGroup) {
+// VirtualUser is the state one worker carries between iterations.
+type VirtualUser struct {
+	ID        int
+	Iteration int
+	Client    *http.Client
+	Vars      map[string]any
+	// Done is set by an executor that has nothing left to run, such as when
+	// a sequential feeder is used up.
+	Done bool
+
+	fed int
+}
+
+func NewVirtualUser(id int, client *http.Client) *VirtualUser {
+	return &VirtualUser{ID: id, Client: client, Vars: make(map[string]any)}
+}
+
+// Executor runs one iteration for a virtual user, reporting every request it
+// makes. start is when the iteration was meant to begin.
+type Executor interface {
+	Execute(vu *VirtualUser, start time.Time, results chan<- Result)
+}
+
+func (s RequestSpec) Execute(vu *VirtualUser, start time.Time, results chan<- Result) {
+	results <- send(vu.Client, s, start)
+}
+
+// Worker runs iterations back to back (closed model) until it has run
+// iterations of them or, when deadline is set, until the deadline passes. It
+// also stops once the executor marks the virtual user done.
+func Worker(exec Executor, vu *VirtualUser, iterations int, deadline time.Time, results chan<- Result, wg *sync.WaitGroup) {
 	defer wg.Done()
-	client := &http.Client{}
-	for i := 0; i < requests; i++ {
-		start := time.Now()
-		resp, err := client.Get(url)
-		duration := time.Since(start)
-		if err != nil {
-			results <- Result{StatusCode: -1, Duration: duration}
-			continue
+	for ; ; vu.Iteration++ {
+		if vu.Done || deadline.IsZero() && vu.Iteration >= iterations || !deadline.IsZero() && !time.Now().Before(deadline) {
+			return
+		}
+		exec.Execute(vu, time.Now(), results)
+	}
+}
+
+// OpenLoad starts iterations on the schedule regardless of how fast the
+// target answers (open model), using up to workers concurrent iterations.
+// Latency is measured from the intended start time, so iterations delayed
+// because every worker was busy count their time in the queue instead of
+// hiding it.
+func OpenLoad(exec Executor, schedule RateSchedule, workers int, client *http.Client, results chan<- Result) {
+	jobs := make(chan time.Time, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(vu *VirtualUser) {
+			defer wg.Done()
+			for intended := range jobs {
+				if vu.Done {
+					continue
+				}
+				exec.Execute(vu, intended, results)
+				vu.Iteration++
+			}
+		}(NewVirtualUser(i, client))
+	}
+
+	start := time.Now()
+	for n := 0; ; n++ {
+		at, ok := schedule.At(n)
+		if !ok {
+			break
 		}
-		results <- Result{StatusCode: resp.StatusCode, Duration: duration}
-		resp.Body.Close()
+		intended := start.Add(at)
+		time.Sleep(time.Until(intended))
+		jobs <- intended
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+func send(client *http.Client, spec RequestSpec, start time.Time) Result {
+	res, _ := do(client, spec, start, false)
+	return res
+}
+
+// do performs the request, returning the response body only when keepBody is
+// set or the spec has assertions, so plain load does not buffer every
+// response.
+func do(client *http.Client, spec RequestSpec, start time.Time, keepBody bool) (Result, []byte) {
+	req, err := spec.NewRequest()
+	if err != nil {
+		return unsentResult(err), nil
+	}
+	trace := &phaseTrace{sent: time.Now()}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace.clientTrace()))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		res := errorResult(ClassifyError(err), err)
+		res.Duration = time.Since(start)
+		res.Phases = trace.phases(time.Time{})
+		res.Connected, res.Reused = trace.connected, trace.reused
+		return res, nil
+	}
+	defer resp.Body.Close()
+
+	var body []byte
+	if keepBody || spec.Assert != nil {
+		body, _ = io.ReadAll(resp.Body)
+	} else {
+		io.Copy(io.Discard, resp.Body)
+	}
+	end := time.Now()
+	res := Result{
+		StatusCode: resp.StatusCode,
+		Duration:   end.Sub(start),
+		Phases:     trace.phases(end),
+		Connected:  trace.connected,
+		Reused:     trace.reused,
+	}
+	if spec.Assert != nil {
+		res.Assertion, res.StatusChecked = spec.Assert.Check(resp.StatusCode, body)
 	}
+	return res, body
 }
@@ -0,0 +1,190 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type metricKind int
+
+const (
+	kindCount metricKind = iota
+	kindDuration
+	kindRate
+)
+
+type metric struct {
+	kind  metricKind
+	value func(Report) float64
+}
+
+var metrics = map[string]metric{
+	"min":          {kindDuration, func(r Report) float64 { return float64(r.Min) }},
+	"max":          {kindDuration, func(r Report) float64 { return float64(r.Max) }},
+	"mean":         {kindDuration, func(r Report) float64 { return float64(r.Mean) }},
+	"p50":          {kindDuration, func(r Report) float64 { return float64(r.P50) }},
+	"p75":          {kindDuration, func(r Report) float64 { return float64(r.P75) }},
+	"p90":          {kindDuration, func(r Report) float64 { return float64(r.P90) }},
+	"p95":          {kindDuration, func(r Report) float64 { return float64(r.P95) }},
+	"p99":          {kindDuration, func(r Report) float64 { return float64(r.P99) }},
+	"p99.9":        {kindDuration, func(r Report) float64 { return float64(r.P999) }},
+	"p999":         {kindDuration, func(r Report) float64 { return float64(r.P999) }},
+	"rps":          {kindCount, func(r Report) float64 { return r.RPS }},
+	"requests":     {kindCount, func(r Report) float64 { return float64(r.TotalRequests) }},
+	"failed":       {kindCount, func(r Report) float64 { return float64(r.FailedCount) }},
+	"error_rate":   {kindRate, func(r Report) float64 { return r.ErrorRate }},
+	"success_rate": {kindRate, func(r Report) float64 { return 1 - r.ErrorRate }},
+}
+
+// Operators are matched longest first so "<=" is not read as "<".
+var operators = []string{"<=", ">=", "==", "!=", "<", ">"}
+
+// Threshold is a pass/fail condition on a report metric, written as e.g.
+// "p95<200ms", "error_rate<1%" or "rps>500".
+type Threshold struct {
+	Expr   string
+	Metric string
+	Op     string
+	Value  float64
+}
+
+type ThresholdResult struct {
+	Expr   string `json:"Expr"`
+	Actual string `json:"Actual"`
+	Passed bool   `json:"Passed"`
+}
+
+func ParseThreshold(expr string) (Threshold, error) {
+	compact := strings.ReplaceAll(strings.TrimSpace(expr), " ", "")
+	for _, op := range operators {
+		name, raw, ok := strings.Cut(compact, op)
+		if !ok {
+			continue
+		}
+		name = strings.ToLower(name)
+		m, ok := metrics[name]
+		if !ok {
+			return Threshold{}, fmt.Errorf("threshold %q: unknown metric %q", expr, name)
+		}
+		value, err := parseThresholdValue(m.kind, raw)
+		if err != nil {
+			return Threshold{}, fmt.Errorf("threshold %q: %w", expr, err)
+		}
+		return Threshold{Expr: compact, Metric: name, Op: op, Value: value}, nil
+	}
+	return Threshold{}, fmt.Errorf("threshold %q: missing comparison operator", expr)
+}
+
+func parseThresholdValue(kind metricKind, raw string) (float64, error) {
+	switch {
+	case kind == kindDuration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return 0, fmt.Errorf("expected a duration such as 200ms, got %q", raw)
+		}
+		return float64(d), nil
+	case kind == kindRate && strings.HasSuffix(raw, "%"):
+		v, err := strconv.ParseFloat(strings.TrimSuffix(raw, "%"), 64)
+		return v / 100, err
+	default:
+		return strconv.ParseFloat(raw, 64)
+	}
+}
+
+// LoadThresholds reads one threshold per line, ignoring blank lines and #
+// comments. A leading "- " is accepted so YAML lists work too.
+func LoadThresholds(path string) ([]Threshold, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var thresholds []Threshold
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "- "))
+		if line == "" {
+			continue
+		}
+		t, err := ParseThreshold(line)
+		if err != nil {
+			return nil, err
+		}
+		thresholds = append(thresholds, t)
+	}
+	return thresholds, scanner.Err()
+}
+
+func (t Threshold) Evaluate(r Report) ThresholdResult {
+	m := metrics[t.Metric]
+	actual := m.value(r)
+
+	var passed bool
+	switch t.Op {
+	case "<":
+		passed = actual < t.Value
+	case "<=":
+		passed = actual <= t.Value
+	case ">":
+		passed = actual > t.Value
+	case ">=":
+		passed = actual >= t.Value
+	case "==":
+		passed = actual == t.Value
+	case "!=":
+		passed = actual != t.Value
+	}
+	return ThresholdResult{Expr: t.Expr, Actual: formatMetric(m.kind, actual), Passed: passed}
+}
+
+func formatMetric(kind metricKind, v float64) string {
+	switch kind {
+	case kindDuration:
+		return time.Duration(v).String()
+	case kindRate:
+		return strconv.FormatFloat(v*100, 'f', 2, 64) + "%"
+	default:
+		if v == math.Trunc(v) {
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	}
+}
+
+// CheckThresholds evaluates every threshold and reports whether all passed.
+func CheckThresholds(r Report, thresholds []Threshold) ([]ThresholdResult, bool) {
+	results := make([]ThresholdResult, len(thresholds))
+	ok := true
+	for i, t := range thresholds {
+		results[i] = t.Evaluate(r)
+		ok = ok && results[i].Passed
+	}
+	return results, ok
+}
+
+// ThresholdFlags collects repeated --threshold flags.
+type ThresholdFlags []Threshold
+
+func (t *ThresholdFlags) String() string {
+	exprs := make([]string, len(*t))
+	for i, th := range *t {
+		exprs[i] = th.Expr
+	}
+	return strings.Join(exprs, ", ")
+}
+
+func (t *ThresholdFlags) Set(value string) error {
+	th, err := ParseThreshold(value)
+	if err != nil {
+		return err
+	}
+	*t = append(*t, th)
+	return nil
+}
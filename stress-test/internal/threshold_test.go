@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseThreshold(t *testing.T) {
+	cases := []struct {
+		expr    string
+		want    Threshold
+		wantErr bool
+	}{
+		{expr: "p95<200ms", want: Threshold{Expr: "p95<200ms", Metric: "p95", Op: "<", Value: float64(200 * time.Millisecond)}},
+		{expr: "p95<=200ms", want: Threshold{Expr: "p95<=200ms", Metric: "p95", Op: "<=", Value: float64(200 * time.Millisecond)}},
+		{expr: "rps >= 500", want: Threshold{Expr: "rps>=500", Metric: "rps", Op: ">=", Value: 500}},
+		{expr: "failed==0", want: Threshold{Expr: "failed==0", Metric: "failed", Op: "==", Value: 0}},
+		{expr: "failed!=3", want: Threshold{Expr: "failed!=3", Metric: "failed", Op: "!=", Value: 3}},
+		{expr: "P99.9>1s", want: Threshold{Expr: "P99.9>1s", Metric: "p99.9", Op: ">", Value: float64(time.Second)}},
+		{expr: "error_rate<1%", want: Threshold{Expr: "error_rate<1%", Metric: "error_rate", Op: "<", Value: 0.01}},
+		{expr: "error_rate<0.5%", want: Threshold{Expr: "error_rate<0.5%", Metric: "error_rate", Op: "<", Value: 0.005}},
+		{expr: "success_rate>0.99", want: Threshold{Expr: "success_rate>0.99", Metric: "success_rate", Op: ">", Value: 0.99}},
+		{expr: "p95<200", wantErr: true},
+		{expr: "rps>5%", wantErr: true},
+		{expr: "error_rate<x%", wantErr: true},
+		{expr: "latency<1s", wantErr: true},
+		{expr: "p95 200ms", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		got, err := ParseThreshold(tc.expr)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %+v", tc.expr, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("%q: expected %+v, got %+v %v", tc.expr, tc.want, got, err)
+		}
+	}
+}
+
+func TestLoadThresholds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "thresholds.yaml")
+	content := "# service objectives\np95<200ms\n\n- error_rate<1%  # budget\n  - rps>=100\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	thresholds, err := LoadThresholds(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"p95<200ms", "error_rate<1%", "rps>=100"}
+	if len(thresholds) != len(want) {
+		t.Fatalf("Expected %v, got %+v", want, thresholds)
+	}
+	for i, th := range thresholds {
+		if th.Expr != want[i] {
+			t.Errorf("Expected %q, got %q", want[i], th.Expr)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte("p95<200ms\nbogus\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadThresholds(path); err == nil {
+		t.Error("Expected an error for an invalid line")
+	}
+}
+
+func TestCheckThresholds(t *testing.T) {
+	r := Report{P95: 150 * time.Millisecond, ErrorRate: 0.02, RPS: 480.5}
+	cases := []struct {
+		expr   string
+		passed bool
+		actual string
+	}{
+		{"p95<200ms", true, "150ms"},
+		{"p95<=150ms", true, "150ms"},
+		{"error_rate<1%", false, "2.00%"},
+		{"success_rate>=98%", true, "98.00%"},
+		{"rps>500", false, "480.50"},
+	}
+
+	var thresholds []Threshold
+	for _, tc := range cases {
+		th, err := ParseThreshold(tc.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		thresholds = append(thresholds, th)
+	}
+	results, ok := CheckThresholds(r, thresholds)
+	if ok {
+		t.Error("Expected the failing thresholds to fail the run")
+	}
+	for i, tc := range cases {
+		if results[i].Passed != tc.passed || results[i].Actual != tc.actual {
+			t.Errorf("%s: expected passed=%v actual=%s, got %+v", tc.expr, tc.passed, tc.actual, results[i])
+		}
+	}
+}
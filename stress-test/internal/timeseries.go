@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IntervalStats is the per-interval breakdown kept in the report, so latency
+// degrading during the run shows up next to the whole-run aggregates.
+type IntervalStats struct {
+	Elapsed     time.Duration `json:"Elapsed"`
+	Requests    int64         `json:"Requests"`
+	RPS         float64       `json:"RPS"`
+	Failed      int           `json:"Failed"`
+	StatusCodes map[int]int   `json:"StatusCodes"`
+	P50         time.Duration `json:"P50"`
+	P90         time.Duration `json:"P90"`
+	P95         time.Duration `json:"P95"`
+	P99         time.Duration `json:"P99"`
+	Max         time.Duration `json:"Max"`
+}
+
+func intervalStats(elapsed time.Duration, requests int64, rps float64, h *Histogram, codes map[int]int, failed int) IntervalStats {
+	return IntervalStats{
+		Elapsed:     elapsed,
+		Requests:    requests,
+		RPS:         rps,
+		Failed:      failed,
+		StatusCodes: codes,
+		P50:         h.Percentile(50),
+		P90:         h.Percentile(90),
+		P95:         h.Percentile(95),
+		P99:         h.Percentile(99),
+		Max:         h.Max,
+	}
+}
+
+// writeSeriesCSV appends the time series as a second table after a blank
+// line.
+func writeSeriesCSV(w *csv.Writer, series []IntervalStats) {
+	if len(series) == 0 {
+		return
+	}
+	w.Write(nil)
+	w.Write([]string{"Elapsed", "Requests", "RPS", "Failed", "P50", "P90", "P95", "P99", "Max", "StatusCodes"})
+	for _, s := range series {
+		w.Write([]string{
+			s.Elapsed.Round(time.Millisecond).String(),
+			strconv.FormatInt(s.Requests, 10),
+			strconv.FormatFloat(s.RPS, 'f', 1, 64),
+			strconv.Itoa(s.Failed),
+			s.P50.String(),
+			s.P90.String(),
+			s.P95.String(),
+			s.P99.String(),
+			s.Max.String(),
+			formatCodes(s.StatusCodes),
+		})
+	}
+}
+
+func formatCodes(codes map[int]int) string {
+	keys := make([]int, 0, len(codes))
+	for code := range codes {
+		keys = append(keys, code)
+	}
+	sort.Ints(keys)
+	parts := make([]string, len(keys))
+	for i, code := range keys {
+		parts[i] = fmt.Sprintf("%d:%d", code, codes[code])
+	}
+	return strings.Join(parts, " ")
+}
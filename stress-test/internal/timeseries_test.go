@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnapshotAggregatesIntervals(t *testing.T) {
+	ms := time.Millisecond
+	intervals := []struct {
+		results []Result
+		length  time.Duration
+		want    IntervalStats
+	}{
+		{
+			[]Result{{StatusCode: 200, Duration: 10 * ms}, {StatusCode: 200, Duration: 20 * ms}, {StatusCode: 503, Duration: 40 * ms}, {StatusCode: 200, Duration: 30 * ms}},
+			time.Second,
+			IntervalStats{Elapsed: time.Second, Requests: 4, RPS: 4, Failed: 1, StatusCodes: map[int]int{200: 3, 503: 1}, Max: 40 * ms},
+		},
+		{
+			nil,
+			time.Second,
+			IntervalStats{Elapsed: 2 * time.Second, StatusCodes: map[int]int{}},
+		},
+		{
+			[]Result{{StatusCode: 200, Duration: 5 * ms}, {StatusCode: -1, Duration: time.Second, Error: ErrorTimeout}},
+			500 * ms,
+			IntervalStats{Elapsed: 2500 * ms, Requests: 2, RPS: 4, Failed: 1, StatusCodes: map[int]int{200: 1, -1: 1}, Max: time.Second},
+		},
+	}
+
+	rec := NewRecorder()
+	now := time.Unix(1000, 0)
+	rec.start, rec.last = now, now
+	for _, iv := range intervals {
+		for _, res := range iv.results {
+			rec.Record(res)
+		}
+		now = now.Add(iv.length)
+		rec.Snapshot(now)
+	}
+
+	series := rec.Report(now.Sub(rec.start), false).TimeSeries
+	if len(series) != len(intervals) {
+		t.Fatalf("Expected %d intervals, got %+v", len(intervals), series)
+	}
+	for i, iv := range intervals {
+		got := series[i]
+		// Percentiles come from the histogram buckets; only their order is
+		// checked here.
+		if !(got.P50 <= got.P90 && got.P90 <= got.P95 && got.P95 <= got.P99 && got.P99 <= got.Max) {
+			t.Errorf("Expected ordered percentiles in interval %d, got %+v", i, got)
+		}
+		got.P50, got.P90, got.P95, got.P99 = 0, 0, 0, 0
+		if !reflect.DeepEqual(got, iv.want) {
+			t.Errorf("Expected interval %d to be %+v, got %+v", i, iv.want, got)
+		}
+	}
+}
+
+func sampleSeries() []IntervalStats {
+	ms := time.Millisecond
+	return []IntervalStats{
+		{Elapsed: time.Second, Requests: 10, RPS: 10, Failed: 1, StatusCodes: map[int]int{503: 1, 200: 9}, P50: 5 * ms, P90: 8 * ms, P95: 9 * ms, P99: 12 * ms, Max: 15 * ms},
+		{Elapsed: 2*time.Second + 400*time.Microsecond, Requests: 12, RPS: 12.5, StatusCodes: map[int]int{200: 12}, P50: 6 * ms, P90: 9 * ms, P95: 10 * ms, P99: 11 * ms, Max: 11 * ms},
+	}
+}
+
+func TestWriteSeriesCSV(t *testing.T) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	writeSeriesCSV(w, sampleSeries())
+	w.Flush()
+
+	want := "\n" +
+		"Elapsed,Requests,RPS,Failed,P50,P90,P95,P99,Max,StatusCodes\n" +
+		"1s,10,10.0,1,5ms,8ms,9ms,12ms,15ms,200:9 503:1\n" +
+		"2s,12,12.5,0,6ms,9ms,10ms,11ms,11ms,200:12\n"
+	if buf.String() != want {
+		t.Errorf("Expected\n%q\ngot\n%q", want, buf.String())
+	}
+
+	buf.Reset()
+	writeSeriesCSV(w, nil)
+	w.Flush()
+	if buf.Len() != 0 {
+		t.Errorf("Expected nothing written without a time series, got %q", buf.String())
+	}
+}
+
+func TestTimeSeriesJSON(t *testing.T) {
+	data, err := json.Marshal(Report{TimeSeries: sampleSeries()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"TimeSeries":[{"Elapsed":1000000000,"Requests":10,"RPS":10,"Failed":1,"StatusCodes":{"200":9,"503":1}`) {
+		t.Errorf("Expected the time series in the JSON report, got %s", data)
+	}
+
+	var decoded Report
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded.TimeSeries, sampleSeries()) {
+		t.Errorf("Expected the time series to round-trip, got %+v", decoded.TimeSeries)
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	report := Report{
+		Protocol:      ProtocolHTTP,
+		TotalRequests: 22,
+		SuccessCount:  21,
+		FailedCount:   1,
+		StatusCodes:   map[int]int{200: 21, 503: 1},
+		Errors:        map[ErrorCategory]*ErrorSummary{ErrorTimeout: {Count: 1, Sample: "<deadline>"}},
+		TimeSeries:    sampleSeries(),
+	}
+
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, report); err != nil {
+		t.Fatal(err)
+	}
+	page := buf.String()
+	for _, want := range []string{"<!DOCTYPE html>", "<td>22</td>", "Throughput", "Latency", "Status codes", "<polyline", "&lt;deadline&gt;"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("Expected the page to contain %q", want)
+		}
+	}
+	if strings.Count(page, "<svg") != 3 {
+		t.Errorf("Expected three charts, got %d", strings.Count(page, "<svg"))
+	}
+
+	buf.Reset()
+	if err := WriteHTML(&buf, Report{Protocol: ProtocolHTTP}); err != nil {
+		t.Errorf("Expected an empty report to render, got %v", err)
+	}
+}